@@ -7,3 +7,6 @@ var BuiltInQueries string
 
 //go:embed templates/report.html
 var ReportTemplate string
+
+//go:embed templates/flow.html
+var FlowTemplate string
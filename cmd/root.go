@@ -8,8 +8,10 @@ import (
 
 	"github.com/charmbracelet/log"
 
+	"github.com/aorith/varnishlog-tui/internal/tx"
 	"github.com/aorith/varnishlog-tui/internal/ui"
 	"github.com/aorith/varnishlog-tui/internal/ui/components/queryloader"
+	"github.com/aorith/varnishlog-tui/internal/util"
 	tea "github.com/charmbracelet/bubbletea"
 )
 
@@ -23,12 +25,24 @@ var (
 	debugMode   *bool
 	showVersion *bool
 	queriesFile *string
+	vslFile     *string
+	maxTxs      *int
+	maxBytes    *string
+	maxAge      *time.Duration
+	spillDir    *string
+	maxSpill    *string
 )
 
 func init() {
 	debugMode = flag.Bool("debug", false, "enable debug logging")
 	showVersion = flag.Bool("version", false, "show version information and exit")
 	queriesFile = flag.String("file", "", "path to a YAML file containing queries")
+	vslFile = flag.String("read", "", "path to a binary VSL file written by varnishlog -w")
+	maxTxs = flag.Int("max-txs", 0, "maximum number of transactions kept in memory, 0 for no limit")
+	maxBytes = flag.String("max-bytes", "", "maximum size of the raw log kept in memory, e.g. 512MB")
+	maxAge = flag.Duration("max-age", 0, "maximum time a transaction is kept in memory, e.g. 30m")
+	spillDir = flag.String("spill-dir", "", "directory where the raw log of evicted transactions is written")
+	maxSpill = flag.String("max-spill", "1GB", "maximum disk used in -spill-dir, the oldest segments are deleted, 0 for no limit")
 }
 
 func Execute() {
@@ -60,5 +74,27 @@ func Execute() {
 		}
 	}
 
-	ui.StartUI(configQueries)
+	if *vslFile != "" {
+		if _, err := os.Stat(*vslFile); err != nil {
+			log.Fatalf("Error reading VSL file: %v", err)
+		}
+	}
+
+	retention := tx.Retention{
+		MaxTxs:   *maxTxs,
+		MaxAge:   *maxAge,
+		SpillDir: *spillDir,
+	}
+	if *maxBytes != "" {
+		retention.MaxBytes, err = util.ParseByteSize(*maxBytes)
+		if err != nil {
+			log.Fatalf("Error parsing -max-bytes: %v", err)
+		}
+	}
+	retention.MaxSpillBytes, err = util.ParseByteSize(*maxSpill)
+	if err != nil {
+		log.Fatalf("Error parsing -max-spill: %v", err)
+	}
+
+	ui.StartUI(configQueries, *vslFile, retention)
 }
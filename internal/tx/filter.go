@@ -0,0 +1,473 @@
+package tx
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/aorith/varnishlog-tui/internal/util"
+	"github.com/aorith/varnishlog-tui/pkg/vsl"
+)
+
+// Filter is a parsed filter expression that selects txs by their fields
+//
+//	status>=500 and host~"api" and duration>250ms and outcome=miss
+//	not (method=GET or method=HEAD) and req.User-Agent~"curl"
+//	ts.Fetch>1s or beresp.Cache-Control~"no-store"
+//
+// Comparisons are joined with and, or, not and parentheses. The operators are
+// = != < <= > >= and ~ !~ for regular expressions.
+type Filter struct {
+	expr string
+	root filterNode
+}
+
+// FilterFields describes the fields that can be used in a filter
+var FilterFields = []string{
+	"vxid txid type reason method host url protocol status outcome backend client",
+	"duration ts.<Label> bytes rxbytes errors logs incomplete",
+	"req.<Header> resp.<Header> bereq.<Header> beresp.<Header> obj.<Header>",
+}
+
+// ParseFilter parses a filter expression, the error includes the column of the problem
+func ParseFilter(expr string) (*Filter, error) {
+	tokens, err := lexFilter(expr)
+	if err != nil {
+		return nil, err
+	}
+
+	p := &filterParser{tokens: tokens}
+	root, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if tok := p.peek(); tok.kind != tokEOF {
+		return nil, tok.errorf("unexpected %s", tok)
+	}
+	return &Filter{expr: expr, root: root}, nil
+}
+
+// Match returns true if the tx matches the filter
+func (f *Filter) Match(t *Tx) bool {
+	return f.root.match(t)
+}
+
+// String returns the expression of the filter
+func (f *Filter) String() string {
+	return f.expr
+}
+
+/* Tokens */
+
+type tokenKind int
+
+const (
+	tokEOF tokenKind = iota
+	tokWord
+	tokString
+	tokOp
+	tokLParen
+	tokRParen
+)
+
+type token struct {
+	kind  tokenKind
+	value string
+	col   int
+}
+
+func (tok token) String() string {
+	switch tok.kind {
+	case tokEOF:
+		return "end of expression"
+	case tokString:
+		return strconv.Quote(tok.value)
+	default:
+		return fmt.Sprintf("%q", tok.value)
+	}
+}
+
+func (tok token) errorf(format string, args ...any) error {
+	return fmt.Errorf("col %d: %s", tok.col, fmt.Sprintf(format, args...))
+}
+
+// isKeyword returns true if the token is the given logical operator (case insensitive)
+func (tok token) isKeyword(keyword string) bool {
+	return tok.kind == tokWord && strings.EqualFold(tok.value, keyword)
+}
+
+var filterOps = []string{"<=", ">=", "!=", "!~", "=", "<", ">", "~"}
+
+func lexFilter(expr string) ([]token, error) {
+	var (
+		tokens []token
+		runes  = []rune(expr)
+	)
+
+	for i := 0; i < len(runes); {
+		r := runes[i]
+		col := i + 1
+		switch {
+		case unicode.IsSpace(r):
+			i++
+		case r == '(':
+			tokens = append(tokens, token{kind: tokLParen, value: "(", col: col})
+			i++
+		case r == ')':
+			tokens = append(tokens, token{kind: tokRParen, value: ")", col: col})
+			i++
+		case r == '"' || r == '\'':
+			var sb strings.Builder
+			quote := r
+			i++
+			for ; i < len(runes) && runes[i] != quote; i++ {
+				if runes[i] == '\\' && i+1 < len(runes) {
+					i++
+				}
+				sb.WriteRune(runes[i])
+			}
+			if i == len(runes) {
+				return nil, fmt.Errorf("col %d: unterminated string", col)
+			}
+			i++
+			tokens = append(tokens, token{kind: tokString, value: sb.String(), col: col})
+		case strings.ContainsRune("<>=!~", r):
+			op := string(r)
+			for _, o := range filterOps {
+				if strings.HasPrefix(string(runes[i:]), o) {
+					op = o
+					break
+				}
+			}
+			if op == "!" {
+				return nil, fmt.Errorf("col %d: unknown operator \"!\", use not", col)
+			}
+			tokens = append(tokens, token{kind: tokOp, value: op, col: col})
+			i += len(op)
+		default:
+			start := i
+			for i < len(runes) && !unicode.IsSpace(runes[i]) && !strings.ContainsRune("()\"'<>=!~", runes[i]) {
+				i++
+			}
+			tokens = append(tokens, token{kind: tokWord, value: string(runes[start:i]), col: col})
+		}
+	}
+
+	return append(tokens, token{kind: tokEOF, col: len(runes) + 1}), nil
+}
+
+/* Parser */
+
+type filterParser struct {
+	tokens []token
+	pos    int
+}
+
+func (p *filterParser) peek() token {
+	return p.tokens[p.pos]
+}
+
+func (p *filterParser) next() token {
+	tok := p.tokens[p.pos]
+	if tok.kind != tokEOF {
+		p.pos++
+	}
+	return tok
+}
+
+func (p *filterParser) parseOr() (filterNode, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for p.peek().isKeyword("or") {
+		p.next()
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		left = orNode{left, right}
+	}
+	return left, nil
+}
+
+func (p *filterParser) parseAnd() (filterNode, error) {
+	left, err := p.parseNot()
+	if err != nil {
+		return nil, err
+	}
+	for p.peek().isKeyword("and") {
+		p.next()
+		right, err := p.parseNot()
+		if err != nil {
+			return nil, err
+		}
+		left = andNode{left, right}
+	}
+	return left, nil
+}
+
+func (p *filterParser) parseNot() (filterNode, error) {
+	if p.peek().isKeyword("not") {
+		p.next()
+		node, err := p.parseNot()
+		if err != nil {
+			return nil, err
+		}
+		return notNode{node}, nil
+	}
+	return p.parsePrimary()
+}
+
+func (p *filterParser) parsePrimary() (filterNode, error) {
+	tok := p.next()
+	switch tok.kind {
+	case tokLParen:
+		node, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if closing := p.next(); closing.kind != tokRParen {
+			return nil, closing.errorf("expected \")\", got %s", closing)
+		}
+		return node, nil
+	case tokWord:
+		return p.parseComparison(tok)
+	default:
+		return nil, tok.errorf("expected a field, got %s", tok)
+	}
+}
+
+func (p *filterParser) parseComparison(fieldTok token) (filterNode, error) {
+	field, ok := lookupFilterField(fieldTok.value)
+	if !ok {
+		return nil, fieldTok.errorf("unknown field %q", fieldTok.value)
+	}
+
+	opTok := p.next()
+	if opTok.kind != tokOp {
+		return nil, opTok.errorf("expected an operator after %s, got %s", fieldTok.value, opTok)
+	}
+
+	valueTok := p.next()
+	if valueTok.kind != tokWord && valueTok.kind != tokString {
+		return nil, valueTok.errorf("expected a value after %s, got %s", opTok.value, valueTok)
+	}
+
+	node := cmpNode{field: field, op: opTok.value, value: valueTok.value}
+	var err error
+	switch {
+	case node.op == "~" || node.op == "!~":
+		node.re, err = regexp.Compile(valueTok.value)
+		if err != nil {
+			return nil, valueTok.errorf("invalid regular expression: %s", err)
+		}
+	case field.kind == kindString:
+		if node.op != "=" && node.op != "!=" {
+			return nil, opTok.errorf("%s is a text field, use = != ~ or !~", fieldTok.value)
+		}
+	case field.kind == kindDuration:
+		var d time.Duration
+		d, err = time.ParseDuration(valueTok.value)
+		if err != nil {
+			return nil, valueTok.errorf("invalid duration %q, e.g. 250ms or 1.5s", valueTok.value)
+		}
+		node.num = int64(d)
+	case field.kind == kindBytes:
+		node.num, err = util.ParseByteSize(valueTok.value)
+		if err != nil {
+			return nil, valueTok.errorf("invalid size %q, e.g. 512 or 10KB", valueTok.value)
+		}
+	case field.kind == kindNumber:
+		node.num, err = strconv.ParseInt(valueTok.value, 10, 64)
+		if err != nil {
+			return nil, valueTok.errorf("invalid number %q", valueTok.value)
+		}
+	}
+	return node, nil
+}
+
+/* Nodes */
+
+type filterNode interface {
+	match(t *Tx) bool
+}
+
+type andNode struct{ left, right filterNode }
+
+func (n andNode) match(t *Tx) bool { return n.left.match(t) && n.right.match(t) }
+
+type orNode struct{ left, right filterNode }
+
+func (n orNode) match(t *Tx) bool { return n.left.match(t) || n.right.match(t) }
+
+type notNode struct{ node filterNode }
+
+func (n notNode) match(t *Tx) bool { return !n.node.match(t) }
+
+type cmpNode struct {
+	field filterField
+	op    string
+	value string
+	num   int64
+	re    *regexp.Regexp
+}
+
+// match compares the values of the field, negated operators match when no value matches
+// the positive one and fields without a value only match negated operators
+func (n cmpNode) match(t *Tx) bool {
+	switch n.op {
+	case "!=":
+		return !n.matchAny(t, "=")
+	case "!~":
+		return !n.matchAny(t, "~")
+	}
+	return n.matchAny(t, n.op)
+}
+
+func (n cmpNode) matchAny(t *Tx, op string) bool {
+	if n.field.kind == kindString || op == "~" {
+		for _, v := range n.field.strings(t) {
+			if (op == "~" && n.re.MatchString(v)) || (op == "=" && v == n.value) {
+				return true
+			}
+		}
+		return false
+	}
+
+	v, ok := n.field.number(t)
+	if !ok {
+		return false
+	}
+	switch op {
+	case "=":
+		return v == n.num
+	case "<":
+		return v < n.num
+	case "<=":
+		return v <= n.num
+	case ">":
+		return v > n.num
+	case ">=":
+		return v >= n.num
+	}
+	return false
+}
+
+/* Fields */
+
+type fieldKind int
+
+const (
+	kindString fieldKind = iota
+	kindNumber
+	kindDuration
+	kindBytes
+)
+
+type filterField struct {
+	kind    fieldKind
+	strings func(t *Tx) []string
+	number  func(t *Tx) (int64, bool)
+}
+
+func stringField(get func(t *Tx) string) filterField {
+	return filterField{kind: kindString, strings: func(t *Tx) []string { return []string{get(t)} }}
+}
+
+func numberField(kind fieldKind, get func(t *Tx) (int64, bool)) filterField {
+	f := filterField{kind: kind, number: get}
+	// Regular expressions match the number as text
+	f.strings = func(t *Tx) []string {
+		if v, ok := get(t); ok {
+			if kind == kindDuration {
+				return []string{time.Duration(v).String()}
+			}
+			return []string{strconv.FormatInt(v, 10)}
+		}
+		return nil
+	}
+	return f
+}
+
+func always(v int64) (int64, bool) { return v, true }
+
+var filterFields = map[string]filterField{
+	"txid":     stringField(func(t *Tx) string { return t.Txid }),
+	"type":     stringField(func(t *Tx) string { return t.RecordType }),
+	"reason":   stringField(func(t *Tx) string { return t.Reason }),
+	"method":   stringField(func(t *Tx) string { return t.Method }),
+	"host":     stringField(func(t *Tx) string { return t.Host }),
+	"url":      stringField(func(t *Tx) string { return t.Url }),
+	"protocol": stringField(func(t *Tx) string { return t.Client.Protocol }),
+	"outcome":  stringField(func(t *Tx) string { return t.CacheOutcome.String() }),
+	"backend":  stringField(func(t *Tx) string { return t.Backend.Name }),
+	"client":   stringField(func(t *Tx) string { return t.Client.IP }),
+	"incomplete": stringField(func(t *Tx) string {
+		return strconv.FormatBool(t.Incomplete)
+	}),
+
+	"vxid":     numberField(kindNumber, func(t *Tx) (int64, bool) { return always(int64(t.Vxid)) }),
+	"status":   numberField(kindNumber, func(t *Tx) (int64, bool) { return t.statusCode() }),
+	"duration": numberField(kindDuration, func(t *Tx) (int64, bool) { return always(int64(t.SumOfSinceLast())) }),
+	"bytes":    numberField(kindBytes, func(t *Tx) (int64, bool) { return always(t.Accounting.Transmitted()) }),
+	"rxbytes":  numberField(kindBytes, func(t *Tx) (int64, bool) { return always(t.Accounting.Received()) }),
+	"errors": numberField(kindNumber, func(t *Tx) (int64, bool) {
+		errors, _ := t.CountEvents()
+		return always(int64(errors))
+	}),
+	"logs": numberField(kindNumber, func(t *Tx) (int64, bool) {
+		_, logs := t.CountEvents()
+		return always(int64(logs))
+	}),
+}
+
+// lookupFilterField returns the field with the given name, timestamps and headers are prefixed
+func lookupFilterField(name string) (filterField, bool) {
+	if f, ok := filterFields[strings.ToLower(name)]; ok {
+		return f, true
+	}
+
+	prefix, rest, found := strings.Cut(name, ".")
+	if !found || rest == "" {
+		return filterField{}, false
+	}
+
+	if strings.EqualFold(prefix, "ts") {
+		return numberField(kindDuration, func(t *Tx) (int64, bool) {
+			for _, ts := range t.Timestamps {
+				if strings.EqualFold(ts.EventLabel, rest) {
+					return int64(ts.SinceLast), true
+				}
+			}
+			return 0, false
+		}), true
+	}
+
+	var headers func(t *Tx) vsl.HeaderSet
+	switch strings.ToLower(prefix) {
+	case "req":
+		headers = func(t *Tx) vsl.HeaderSet { return t.ReqHeaders }
+	case "resp":
+		headers = func(t *Tx) vsl.HeaderSet { return t.RespHeaders }
+	case "bereq":
+		headers = func(t *Tx) vsl.HeaderSet { return t.BereqHeaders }
+	case "beresp":
+		headers = func(t *Tx) vsl.HeaderSet { return t.BerespHeaders }
+	case "obj":
+		headers = func(t *Tx) vsl.HeaderSet { return t.ObjHeaders }
+	default:
+		return filterField{}, false
+	}
+	return filterField{kind: kindString, strings: func(t *Tx) []string {
+		return headers(t).Final.Values(rest)
+	}}, true
+}
+
+// statusCode returns the status of the response, txs without one have no status
+func (t *Tx) statusCode() (int64, bool) {
+	return int64(t.StatusCode), t.StatusCode != 0
+}
@@ -0,0 +1,98 @@
+package tx
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aorith/varnishlog-tui/pkg/vsl"
+)
+
+func newFilterTestTx() *Tx {
+	t := New(vsl.Tx{
+		Txid:         "32770",
+		Vxid:         32770,
+		RecordType:   "req",
+		Method:       "GET",
+		Host:         "api.example.com",
+		Url:          "/v1/items",
+		StatusCode:   503,
+		CacheOutcome: vsl.OutcomeMiss,
+		Timestamps: []vsl.Timestamp{
+			{EventLabel: "Start"},
+			{EventLabel: "Fetch", SinceLast: 300 * time.Millisecond},
+			{EventLabel: "Resp", SinceLast: 10 * time.Millisecond},
+		},
+		Accounting: vsl.RequestAccounting{HeaderBytesTransmitted: 200, BodyBytesTransmitted: 2048},
+		ReqHeaders: vsl.HeaderSet{Final: vsl.Headers{{Name: "User-Agent", Value: "curl/8.5.0"}}},
+	})
+	return &t
+}
+
+// TestFilterMatch tests the evaluation of filter expressions
+func TestFilterMatch(t *testing.T) {
+	tx := newFilterTestTx()
+
+	tests := []struct {
+		expr     string
+		expected bool
+	}{
+		{`status>=500 and host~"api" and duration>250ms and outcome=miss`, true},
+		{`status>=500 and outcome=hit`, false},
+		{`status=503`, true},
+		{`status<500 or method=GET`, true},
+		{`not method=GET`, false},
+		{`not (method=POST or method=PUT)`, true},
+		{`ts.Fetch>250ms and ts.resp<=10ms`, true},
+		{`ts.Process>0s`, false},
+		{`ts.Process!=0s`, true},
+		{`bytes>2KB`, true},
+		{`bytes>3KB`, false},
+		{`req.user-agent~"^curl/"`, true},
+		{`req.Cookie~"."`, false},
+		{`req.Cookie!~"."`, true},
+		{`url='/v1/items' AND type=req`, true},
+		{`vxid~"^327"`, true},
+	}
+
+	for _, tt := range tests {
+		f, err := ParseFilter(tt.expr)
+		if err != nil {
+			t.Errorf("ParseFilter(%q) returned an error: %s", tt.expr, err)
+			continue
+		}
+		if got := f.Match(tx); got != tt.expected {
+			t.Errorf("%q: expected %t, got %t", tt.expr, tt.expected, got)
+		}
+	}
+}
+
+// TestFilterParseErrors tests that malformed expressions report the column of the problem
+func TestFilterParseErrors(t *testing.T) {
+	tests := []struct {
+		expr     string
+		expected string
+	}{
+		{`stat>=500`, `col 1: unknown field "stat"`},
+		{`status>=`, `col 9: expected a value after >=`},
+		{`status>=abc`, `col 9: invalid number "abc"`},
+		{`duration>5`, `col 10: invalid duration "5"`},
+		{`host>"a"`, `col 5: host is a text field`},
+		{`host~"("`, `col 6: invalid regular expression`},
+		{`(status=200`, `col 12: expected ")"`},
+		{`status=200 host=a`, `col 12: unexpected "host"`},
+		{`host="api`, `col 6: unterminated string`},
+		{`!host=a`, `col 1: unknown operator "!"`},
+	}
+
+	for _, tt := range tests {
+		_, err := ParseFilter(tt.expr)
+		if err == nil {
+			t.Errorf("ParseFilter(%q): expected an error", tt.expr)
+			continue
+		}
+		if !strings.HasPrefix(err.Error(), tt.expected) {
+			t.Errorf("ParseFilter(%q): expected %q, got %q", tt.expr, tt.expected, err.Error())
+		}
+	}
+}
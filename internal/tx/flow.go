@@ -0,0 +1,138 @@
+package tx
+
+import (
+	"cmp"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/aorith/varnishlog-tui/internal/util"
+	"github.com/aorith/varnishlog-tui/pkg/vsl"
+)
+
+// FlowEnd is the target of the last transition of a tx, like the final state of mermaid
+const FlowEnd = "[*]"
+
+// FlowEdge is a transition of the VCL state machine and how many times it was taken
+type FlowEdge struct {
+	From   string // Subroutine called, RECV
+	Return string // Its return, hash
+	To     string // Next subroutine called, HASH, or FlowEnd
+	Count  int
+	Share  float64 // Fraction of the transitions leaving From
+}
+
+type flowKey struct {
+	from, ret, to string
+}
+
+// Flow aggregates the VCL transitions of many txs into a weighted graph, it shows
+// which paths of the VCL the traffic takes
+type Flow struct {
+	Txs   int // Txs with transitions
+	edges map[flowKey]int
+	out   map[string]int // Transitions leaving each subroutine
+	order map[string]int // Earliest position of each subroutine in a tx, RECV before HASH
+}
+
+// NewFlow returns the flow of the txs, the ones without transitions are ignored
+func NewFlow(txs []*Tx) *Flow {
+	f := &Flow{
+		edges: make(map[flowKey]int),
+		out:   make(map[string]int),
+		order: make(map[string]int),
+	}
+	for _, t := range txs {
+		f.Add(t)
+	}
+	return f
+}
+
+// Add counts the transitions of the tx
+func (f *Flow) Add(t *Tx) {
+	if len(t.Transitions) == 0 {
+		return
+	}
+	f.Txs++
+
+	for i, tr := range t.Transitions {
+		to := FlowEnd
+		if i+1 < len(t.Transitions) {
+			to = t.Transitions[i+1].Call
+		}
+		f.edges[flowKey{from: tr.Call, ret: tr.Return, to: to}]++
+		f.out[tr.Call]++
+		if pos, ok := f.order[tr.Call]; !ok || i < pos {
+			f.order[tr.Call] = i
+		}
+	}
+}
+
+// Edges returns the transitions grouped by the subroutine they leave, in the order
+// the subroutines are called and then by the times they were taken
+func (f *Flow) Edges() []FlowEdge {
+	edges := make([]FlowEdge, 0, len(f.edges))
+	for k, count := range f.edges {
+		edges = append(edges, FlowEdge{
+			From:   k.from,
+			Return: k.ret,
+			To:     k.to,
+			Count:  count,
+			Share:  float64(count) / float64(f.out[k.from]),
+		})
+	}
+	slices.SortFunc(edges, func(a, b FlowEdge) int {
+		return cmp.Or(
+			cmp.Compare(f.order[a.From], f.order[b.From]),
+			cmp.Compare(f.out[b.From], f.out[a.From]),
+			strings.Compare(a.From, b.From),
+			cmp.Compare(b.Count, a.Count),
+			strings.Compare(a.Return, b.Return),
+			strings.Compare(a.To, b.To),
+		)
+	})
+	return edges
+}
+
+// GenerateFlowTable generates an ASCII table with the transitions and their share
+//
+//	Subroutine | Return | Next     | Count | Share
+//	-----------------------------------------------
+//	vcl_recv   | hash   | vcl_hash | 920   | 92.0%
+//	vcl_recv   | pass   | vcl_pass | 80    | 8.0%
+func (f *Flow) GenerateFlowTable() string {
+	headers, rows := f.flowRows()
+	if len(rows) == 0 {
+		return ""
+	}
+	return util.GenerateTable(headers, rows)
+}
+
+// flowRows returns the headers and rows of the table of transitions
+func (f *Flow) flowRows() ([]string, [][]string) {
+	edges := f.Edges()
+	rows := make([][]string, len(edges))
+	for i, e := range edges {
+		to := "end"
+		if e.To != FlowEnd {
+			to = vsl.SubName(e.To)
+		}
+		rows[i] = []string{vsl.SubName(e.From), e.Return, to, fmt.Sprintf("%d", e.Count), fmt.Sprintf("%.1f%%", e.Share*100)}
+	}
+	return []string{"Subroutine", "Return", "Next", "Count", "Share"}, rows
+}
+
+// generateFlowDiagram generates a mermaid diagram of the transitions labeled with their share
+func (f *Flow) generateFlowDiagram() string {
+	edges := f.Edges()
+	if len(edges) == 0 {
+		return ""
+	}
+
+	var s strings.Builder
+	s.WriteString("stateDiagram\ndirection LR\n")
+	for _, e := range edges {
+		s.WriteString(fmt.Sprintf("%s --> %s: <em>%s</em> %.1f%% (%d)\n", e.From, e.To, e.Return, e.Share*100, e.Count))
+	}
+	return s.String()
+}
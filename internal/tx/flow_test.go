@@ -0,0 +1,73 @@
+package tx
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/aorith/varnishlog-tui/pkg/vsl"
+)
+
+// newTestFlowTx returns a tx with the transitions given as call and return pairs
+func newTestFlowTx(calls ...string) *Tx {
+	t := newTestTx("1", 1)
+	for i := 0; i+1 < len(calls); i += 2 {
+		t.Transitions = append(t.Transitions, vsl.VCLTransition{Call: calls[i], Return: calls[i+1]})
+	}
+	return &t
+}
+
+// TestFlow tests the counts and shares of the transitions of many txs
+func TestFlow(t *testing.T) {
+	var txs []*Tx
+	for i := 0; i < 9; i++ {
+		txs = append(txs, newTestFlowTx("RECV", "hash", "HASH", "lookup", "HIT", "deliver", "DELIVER", "deliver"))
+	}
+	txs = append(txs,
+		newTestFlowTx("RECV", "pass", "PASS", "fetch", "DELIVER", "deliver"),
+		newTestFlowTx("BACKEND_FETCH", "fetch", "BACKEND_RESPONSE", "deliver"),
+		newTestFlowTx(), // No transitions
+	)
+
+	f := NewFlow(txs)
+	if f.Txs != 11 {
+		t.Errorf("txs: expected 11, got %d", f.Txs)
+	}
+
+	tests := []struct {
+		from, ret, to string
+		count         int
+		share         float64
+	}{
+		{"RECV", "hash", "HASH", 9, 0.9},
+		{"RECV", "pass", "PASS", 1, 0.1},
+		{"BACKEND_FETCH", "fetch", "BACKEND_RESPONSE", 1, 1},
+		{"HASH", "lookup", "HIT", 9, 1},
+		{"BACKEND_RESPONSE", "deliver", FlowEnd, 1, 1},
+		{"PASS", "fetch", "DELIVER", 1, 1},
+		{"DELIVER", "deliver", FlowEnd, 10, 1},
+		{"HIT", "deliver", "DELIVER", 9, 1},
+	}
+
+	edges := f.Edges()
+	if len(edges) != len(tests) {
+		t.Fatalf("expected %d edges, got %d: %v", len(tests), len(edges), edges)
+	}
+	for i, tt := range tests {
+		e := edges[i]
+		if e.From != tt.from || e.Return != tt.ret || e.To != tt.to {
+			t.Errorf("edge %d: expected %s -%s-> %s, got %s -%s-> %s", i, tt.from, tt.ret, tt.to, e.From, e.Return, e.To)
+			continue
+		}
+		if e.Count != tt.count || e.Share != tt.share {
+			t.Errorf("edge %d: expected %d %.2f, got %d %.2f", i, tt.count, tt.share, e.Count, e.Share)
+		}
+	}
+
+	table := f.GenerateFlowTable()
+	if lines := strings.Split(table, "\n"); len(lines) < 4 || strings.Join(strings.Fields(lines[3]), " ") != "vcl_recv | hash | vcl_hash | 9 | 90.0%" {
+		t.Errorf("unexpected table:\n%s", table)
+	}
+	if diagram := f.generateFlowDiagram(); !strings.Contains(diagram, "RECV --> HASH: <em>hash</em> 90.0% (9)\n") {
+		t.Errorf("unexpected diagram:\n%s", diagram)
+	}
+}
@@ -0,0 +1,201 @@
+package tx
+
+import (
+	"cmp"
+	"net/url"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/aorith/varnishlog-tui/internal/util"
+)
+
+const (
+	// latencyAccuracy is the relative error of the percentiles
+	latencyAccuracy = 0.01
+
+	// maxLatencyGroups bounds the groups of each grouping, later ones are grouped as StatsOther
+	maxLatencyGroups = 500
+
+	// urlPrefixDepth is the number of path segments that make the URL prefix
+	urlPrefixDepth = 2
+)
+
+// LatencyGroupBy is the field that groups the latencies
+type LatencyGroupBy int
+
+const (
+	GroupByHost      LatencyGroupBy = iota // Host of the requests
+	GroupByURLPrefix                       // First segments of the path of the requests
+	GroupByBackend                         // Backend of the backend requests
+	GroupByOutcome                         // Cache outcome of the requests
+)
+
+var latencyGroupByNames = [...]string{"host", "url prefix", "backend", "outcome"}
+
+func (g LatencyGroupBy) String() string {
+	if g < 0 || int(g) >= len(latencyGroupByNames) {
+		return "unknown"
+	}
+	return latencyGroupByNames[g]
+}
+
+// Next returns the following grouping, wrapping around after the last one
+func (g LatencyGroupBy) Next() LatencyGroupBy {
+	return (g + 1) % LatencyGroupBy(len(latencyGroupByNames))
+}
+
+// LatencyMetric is the duration measured
+type LatencyMetric int
+
+const (
+	MetricTotal   LatencyMetric = iota // SumOfSinceLast of the tx
+	MetricFetch                        // Timestamps of the Fetch phase
+	MetricProcess                      // Timestamps of the Process phase
+	MetricResp                         // Timestamps of the Resp phase
+)
+
+var latencyMetricNames = [...]string{"total", PhaseFetch, PhaseProcess, PhaseResp}
+
+func (m LatencyMetric) String() string {
+	if m < 0 || int(m) >= len(latencyMetricNames) {
+		return "unknown"
+	}
+	return latencyMetricNames[m]
+}
+
+// Next returns the following metric, wrapping around after the last one
+func (m LatencyMetric) Next() LatencyMetric {
+	return (m + 1) % LatencyMetric(len(latencyMetricNames))
+}
+
+// LatencyRow holds the percentiles of a group
+type LatencyRow struct {
+	Key   string
+	Count uint64
+	P50   time.Duration
+	P90   time.Duration
+	P99   time.Duration
+	Max   time.Duration
+}
+
+// latencySketches holds a sketch for each metric
+type latencySketches [len(latencyMetricNames)]*util.Sketch
+
+// Latencies keeps a quantile sketch of each metric for every group of every grouping,
+// its memory depends on the number of groups and not on the number of txs
+type Latencies struct {
+	groups [len(latencyGroupByNames)]map[string]*latencySketches
+}
+
+func NewLatencies() *Latencies {
+	l := &Latencies{}
+	for i := range l.groups {
+		l.groups[i] = make(map[string]*latencySketches)
+	}
+	return l
+}
+
+// Add counts the durations of a req in the host, URL prefix and outcome groups,
+// and of a bereq in the backend group
+func (l *Latencies) Add(t *Tx) {
+	if len(t.Timestamps) == 0 {
+		return
+	}
+
+	var (
+		durations [len(latencyMetricNames)]time.Duration
+		phases    [len(latencyMetricNames)]bool // Metrics measured by the tx, a bereq has no Resp phase
+	)
+	durations[MetricTotal], phases[MetricTotal] = t.SumOfSinceLast(), true
+	for _, ts := range t.Timestamps {
+		switch timestampPhase(ts.EventLabel) {
+		case PhaseFetch:
+			durations[MetricFetch] += ts.SinceLast
+			phases[MetricFetch] = true
+		case PhaseProcess:
+			durations[MetricProcess] += ts.SinceLast
+			phases[MetricProcess] = true
+		case PhaseResp:
+			durations[MetricResp] += ts.SinceLast
+			phases[MetricResp] = true
+		}
+	}
+
+	switch t.RecordType {
+	case "req":
+		l.add(GroupByHost, t.Host, durations, phases)
+		l.add(GroupByURLPrefix, URLPrefix(t.Url, urlPrefixDepth), durations, phases)
+		l.add(GroupByOutcome, t.CacheOutcome.String(), durations, phases)
+	case "bereq":
+		l.add(GroupByBackend, t.Backend.Name, durations, phases)
+	}
+}
+
+func (l *Latencies) add(by LatencyGroupBy, key string, durations [len(latencyMetricNames)]time.Duration, phases [len(latencyMetricNames)]bool) {
+	if key == "" {
+		key = "-"
+	}
+	groups := l.groups[by]
+	sketches, ok := groups[key]
+	if !ok {
+		if len(groups) >= maxLatencyGroups {
+			key = StatsOther
+			sketches = groups[key]
+		}
+		if sketches == nil {
+			sketches = &latencySketches{}
+			for i := range sketches {
+				sketches[i] = util.NewSketch(latencyAccuracy)
+			}
+			groups[key] = sketches
+		}
+	}
+	for i, d := range durations {
+		if phases[i] {
+			sketches[i].Add(float64(d))
+		}
+	}
+}
+
+// Rows returns the percentiles of the metric for each group, the groups with more txs first
+func (l *Latencies) Rows(by LatencyGroupBy, metric LatencyMetric) []LatencyRow {
+	rows := make([]LatencyRow, 0, len(l.groups[by]))
+	for key, sketches := range l.groups[by] {
+		s := sketches[metric]
+		if s.Count() == 0 {
+			continue
+		}
+		rows = append(rows, LatencyRow{
+			Key:   key,
+			Count: s.Count(),
+			P50:   time.Duration(s.Quantile(0.5)),
+			P90:   time.Duration(s.Quantile(0.9)),
+			P99:   time.Duration(s.Quantile(0.99)),
+			Max:   time.Duration(s.Max()),
+		})
+	}
+	slices.SortFunc(rows, func(a, b LatencyRow) int {
+		return cmp.Or(cmp.Compare(b.Count, a.Count), strings.Compare(a.Key, b.Key))
+	})
+	return rows
+}
+
+// URLPrefix returns the first depth segments of the path of the URL without the query
+//
+//	URLPrefix("/api/v1/users/42?full=1", 2) == "/api/v1/…"
+//	URLPrefix("/index.html", 2) == "/index.html"
+func URLPrefix(rawURL string, depth int) string {
+	path := rawURL
+	if u, err := url.Parse(rawURL); err == nil {
+		path = u.Path
+	} else if i := strings.IndexByte(path, '?'); i >= 0 {
+		path = path[:i]
+	}
+
+	segments := strings.SplitN(strings.TrimPrefix(path, "/"), "/", depth+1)
+	if len(segments) <= depth {
+		return path
+	}
+	return "/" + strings.Join(segments[:depth], "/") + "/…"
+}
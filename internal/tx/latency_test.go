@@ -0,0 +1,93 @@
+package tx
+
+import (
+	"testing"
+	"time"
+
+	"github.com/aorith/varnishlog-tui/pkg/vsl"
+)
+
+// TestLatencies tests the percentiles of each grouping and metric
+func TestLatencies(t *testing.T) {
+	start := time.Date(2024, 6, 1, 10, 0, 0, 0, time.UTC)
+	l := NewLatencies()
+
+	for i := 1; i <= 100; i++ {
+		req := newTestTx("1", uint64(i))
+		req.RecordType = "req"
+		req.Host = "www.example.com"
+		req.Url = "/api/v1/items?id=1"
+		fetch := time.Duration(i) * time.Millisecond
+		req.Timestamps = newTestTimestamps(start,
+			[]string{"Start", "Req", "Fetch", "Process", "Resp"},
+			[]time.Duration{0, 0, fetch, fetch + time.Millisecond, fetch + 2*time.Millisecond},
+		)
+		l.Add(&req)
+	}
+
+	bereq := newTestTx("2", 200)
+	bereq.RecordType = "bereq"
+	bereq.Backend = vsl.BackendConnection{Name: "boot.default"}
+	bereq.Timestamps = newTestTimestamps(start,
+		[]string{"Start", "Bereq", "Beresp", "BerespBody"},
+		[]time.Duration{0, time.Millisecond, 5 * time.Millisecond, 6 * time.Millisecond},
+	)
+	l.Add(&bereq)
+
+	tests := []struct {
+		by     LatencyGroupBy
+		metric LatencyMetric
+		key    string
+		count  uint64
+		p50    time.Duration
+		max    time.Duration
+	}{
+		{GroupByHost, MetricTotal, "www.example.com", 100, 52 * time.Millisecond, 102 * time.Millisecond},
+		{GroupByURLPrefix, MetricFetch, "/api/v1/…", 100, 50 * time.Millisecond, 100 * time.Millisecond},
+		{GroupByOutcome, MetricResp, "-", 100, time.Millisecond, time.Millisecond},
+		{GroupByBackend, MetricFetch, "boot.default", 1, 5 * time.Millisecond, 5 * time.Millisecond},
+	}
+
+	for _, tt := range tests {
+		rows := l.Rows(tt.by, tt.metric)
+		if len(rows) != 1 {
+			t.Errorf("%s %s: expected 1 row, got %d", tt.by, tt.metric, len(rows))
+			continue
+		}
+		r := rows[0]
+		if r.Key != tt.key || r.Count != tt.count {
+			t.Errorf("%s %s: expected %s with %d txs, got %s with %d", tt.by, tt.metric, tt.key, tt.count, r.Key, r.Count)
+		}
+		if diff := r.P50 - tt.p50; diff < -tt.p50/50 || diff > tt.p50/50 {
+			t.Errorf("%s %s: expected p50 %v within 2%%, got %v", tt.by, tt.metric, tt.p50, r.P50)
+		}
+		if r.Max != tt.max {
+			t.Errorf("%s %s: expected max %v, got %v", tt.by, tt.metric, tt.max, r.Max)
+		}
+	}
+
+	// A bereq has no Resp phase
+	if rows := l.Rows(GroupByBackend, MetricResp); len(rows) != 0 {
+		t.Errorf("expected no Resp rows for the backends, got %v", rows)
+	}
+}
+
+// TestURLPrefix tests the URL prefixes used to group the requests
+func TestURLPrefix(t *testing.T) {
+	tests := []struct {
+		url      string
+		expected string
+	}{
+		{"/api/v1/users/42?full=1", "/api/v1/…"},
+		{"/api/v1", "/api/v1"},
+		{"/index.html?a=b", "/index.html"},
+		{"/", "/"},
+		{"http://www.example.com/static/css/site.css", "/static/css/…"},
+	}
+
+	for _, tt := range tests {
+		if got := URLPrefix(tt.url, 2); got != tt.expected {
+			t.Errorf("URLPrefix(%q, 2): expected %q, got %q", tt.url, tt.expected, got)
+		}
+	}
+}
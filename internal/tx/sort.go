@@ -0,0 +1,50 @@
+package tx
+
+import (
+	"cmp"
+	"strings"
+)
+
+// SortMode is the order of the txs in the list
+type SortMode int
+
+const (
+	SortByArrival  SortMode = iota // Order in which the txs were received, like a tail
+	SortByVxid                     // Numerically by vxid
+	SortByStart                    // Chronologically by the Start timestamp
+	SortByDuration                 // Slowest first
+	SortByStatus                   // Highest status code first
+	SortByBytes                    // Most bytes transmitted first
+)
+
+var sortModeNames = []string{"arrival", "vxid", "start", "duration", "status", "bytes"}
+
+func (m SortMode) String() string {
+	if m < 0 || int(m) >= len(sortModeNames) {
+		return "unknown"
+	}
+	return sortModeNames[m]
+}
+
+// Next returns the following sort mode, wrapping around after the last one
+func (m SortMode) Next() SortMode {
+	return (m + 1) % SortMode(len(sortModeNames))
+}
+
+// compare orders two txs by the sort mode, ties are broken by arrival so the order is total
+func (m SortMode) compare(a, b *Tx) int {
+	var c int
+	switch m {
+	case SortByVxid:
+		c = cmp.Or(cmp.Compare(a.Vxid, b.Vxid), strings.Compare(a.Txid, b.Txid))
+	case SortByStart:
+		c = a.StartTime().Compare(b.StartTime())
+	case SortByDuration:
+		c = cmp.Compare(b.SumOfSinceLast(), a.SumOfSinceLast())
+	case SortByStatus:
+		c = cmp.Compare(b.StatusCode, a.StatusCode)
+	case SortByBytes:
+		c = cmp.Compare(b.Accounting.Transmitted(), a.Accounting.Transmitted())
+	}
+	return cmp.Or(c, cmp.Compare(a.seq, b.seq))
+}
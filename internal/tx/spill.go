@@ -0,0 +1,181 @@
+package tx
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// spillSegmentSize is the size in bytes at which a new segment file is started
+const spillSegmentSize = 64 << 20
+
+// Spill writes the raw log of evicted txs to segment files in a directory, they are plain
+// varnishlog output so they can be reopened with cat from the query editor or searched with grep.
+// Only the range of vxids of each segment is kept in memory, and the oldest segments are
+// deleted when they take more than maxBytes.
+type Spill struct {
+	dir         string
+	prefix      string
+	maxBytes    int64 // Disk used by the segments, no limit if zero
+	segmentSize int64
+	segments    []spillSegment // Oldest first, the last one is being written
+	created     int
+	file        *os.File
+	w           *bufio.Writer
+}
+
+// spillSegment is a segment file and the range of the vxids written to it
+type spillSegment struct {
+	path             string
+	size             int64
+	txs              int
+	minVxid, maxVxid uint64
+}
+
+// NewSpill creates the directory if needed, segments are created once something is spilled
+func NewSpill(dir string, maxBytes int64) (*Spill, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("Error creating spill directory: %s", err.Error())
+	}
+
+	// Small limits still leave room for a few segments, the whole current one is kept
+	segmentSize := int64(spillSegmentSize)
+	if maxBytes > 0 {
+		segmentSize = min(segmentSize, max(maxBytes/4, 1))
+	}
+	return &Spill{
+		dir:         dir,
+		prefix:      fmt.Sprintf("varnishlog-tui-%s-%d", time.Now().Format("20060102-150405"), os.Getpid()),
+		maxBytes:    maxBytes,
+		segmentSize: segmentSize,
+	}, nil
+}
+
+// Write appends the raw log of the txs to the current segment
+func (s *Spill) Write(txs []*Tx) error {
+	if s.file == nil || s.segments[len(s.segments)-1].size >= s.segmentSize {
+		if err := s.rotate(); err != nil {
+			return err
+		}
+	}
+
+	seg := &s.segments[len(s.segments)-1]
+	for _, t := range txs {
+		for _, line := range t.RawTx {
+			n, err := s.w.WriteString(line + "\n")
+			seg.size += int64(n)
+			if err != nil {
+				return fmt.Errorf("Error writing spill segment: %s", err.Error())
+			}
+		}
+		n, err := s.w.WriteString("\n")
+		seg.size += int64(n)
+		if err != nil {
+			return fmt.Errorf("Error writing spill segment: %s", err.Error())
+		}
+
+		seg.txs++
+		if t.Vxid != 0 {
+			if seg.minVxid == 0 || t.Vxid < seg.minVxid {
+				seg.minVxid = t.Vxid
+			}
+			seg.maxVxid = max(seg.maxVxid, t.Vxid)
+		}
+	}
+
+	if err := s.w.Flush(); err != nil {
+		return fmt.Errorf("Error writing spill segment: %s", err.Error())
+	}
+	return nil
+}
+
+// Contains returns true if the vxid is in the range of a segment on disk. Only the ranges
+// are kept, so vxids that were never received, like the ones of txs that did not
+// match the query, are reported too.
+func (s *Spill) Contains(vxid uint64) bool {
+	for _, seg := range s.segments {
+		if seg.txs > 0 && vxid >= seg.minVxid && vxid <= seg.maxVxid {
+			return true
+		}
+	}
+	return false
+}
+
+// Len returns the number of spilled txs in the segments on disk
+func (s *Spill) Len() (n int) {
+	for _, seg := range s.segments {
+		n += seg.txs
+	}
+	return n
+}
+
+// Seal closes the current segment so it is not written while being read
+// and returns the paths of all the segments
+func (s *Spill) Seal() ([]string, error) {
+	if err := s.Close(); err != nil {
+		return nil, err
+	}
+	paths := make([]string, len(s.segments))
+	for i, seg := range s.segments {
+		paths[i] = seg.path
+	}
+	return paths, nil
+}
+
+// Close flushes and closes the current segment, the next Write starts a new one
+func (s *Spill) Close() error {
+	if s.file == nil {
+		return nil
+	}
+	err := s.w.Flush()
+	if cerr := s.file.Close(); err == nil {
+		err = cerr
+	}
+	s.file, s.w = nil, nil
+	if err != nil {
+		return fmt.Errorf("Error closing spill segment: %s", err.Error())
+	}
+	return nil
+}
+
+func (s *Spill) rotate() error {
+	if err := s.Close(); err != nil {
+		return err
+	}
+	if err := s.prune(); err != nil {
+		return err
+	}
+
+	s.created++
+	name := filepath.Join(s.dir, fmt.Sprintf("%s-%04d.log", s.prefix, s.created))
+	f, err := os.OpenFile(name, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0o644)
+	if err != nil {
+		return fmt.Errorf("Error creating spill segment: %s", err.Error())
+	}
+	s.segments = append(s.segments, spillSegment{path: name})
+	s.file = f
+	s.w = bufio.NewWriter(f)
+	return nil
+}
+
+// prune deletes the oldest segments until there is room for a new one within maxBytes
+func (s *Spill) prune() error {
+	if s.maxBytes <= 0 {
+		return nil
+	}
+
+	var total int64
+	for _, seg := range s.segments {
+		total += seg.size
+	}
+	for len(s.segments) > 0 && total+s.segmentSize > s.maxBytes {
+		if err := os.Remove(s.segments[0].path); err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("Error deleting spill segment: %s", err.Error())
+		}
+		total -= s.segments[0].size
+		s.segments = s.segments[1:]
+	}
+	return nil
+}
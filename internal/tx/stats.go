@@ -0,0 +1,198 @@
+package tx
+
+import (
+	"cmp"
+	"slices"
+	"time"
+
+	"github.com/aorith/varnishlog-tui/internal/util"
+	"github.com/aorith/varnishlog-tui/pkg/vsl"
+)
+
+const (
+	// rateWindow is the period used to compute the current requests per second
+	rateWindow = 10 * time.Second
+
+	// maxTrackedKeys bounds the distinct hosts and URLs counted, later ones are counted as StatsOther
+	maxTrackedKeys = 10000
+)
+
+// Series of the response time histogram
+const (
+	HistogramHits = iota
+	HistogramMisses
+)
+
+// StatsOther is the key of the values received once maxTrackedKeys were being counted
+const StatsOther = "(other)"
+
+// Counter counts the occurrences of a key
+type Counter struct {
+	Key   string
+	Count int
+}
+
+// Stats aggregates the txs of a capture, the counters are cumulative and don't
+// decrease when the store evicts txs
+type Stats struct {
+	Reqs          int
+	Bereqs        int
+	Sessions      int
+	StatusClasses [6]int // Index 1 to 5 for 1xx to 5xx, 0 for requests without a status
+	BackendErrors int    // Backend responses with a 5xx status or without a response
+	Outcomes      map[vsl.CacheOutcome]int
+	ClientIn      int64 // Bytes received from the clients
+	ClientOut     int64 // Bytes transmitted to the clients
+	BackendIn     int64 // Bytes received from the backends
+	BackendOut    int64 // Bytes transmitted to the backends
+	First, Last   time.Time
+	Latencies     *Latencies
+	Histogram     *util.LogHistogram // Response time of the reqs from 1µs to 100s, split into hits and the rest
+
+	hosts  map[string]int
+	urls   map[string]int
+	starts map[int64]int // Reqs by the second of their Start inside the rate window, they arrive in End order
+}
+
+func NewStats() *Stats {
+	return &Stats{
+		Outcomes:  make(map[vsl.CacheOutcome]int),
+		Latencies: NewLatencies(),
+		Histogram: util.NewLogHistogram(3, 11, func(v int64) util.ValueProvider { return util.DurationValue(v) }, "hit", "miss"),
+		hosts:     make(map[string]int),
+		urls:      make(map[string]int),
+		starts:    make(map[int64]int),
+	}
+}
+
+// Add counts a tx
+func (s *Stats) Add(t *Tx) {
+	s.Latencies.Add(t)
+
+	switch t.RecordType {
+	case "sess":
+		s.Sessions++
+		return
+	case "bereq":
+		s.Bereqs++
+		s.BackendIn += t.Accounting.Received()
+		s.BackendOut += t.Accounting.Transmitted()
+		if t.StatusCode == 0 || t.StatusCode >= 500 {
+			s.BackendErrors++
+		}
+		return
+	case "req":
+	default:
+		return
+	}
+
+	s.Reqs++
+	s.ClientIn += t.Accounting.Received()
+	s.ClientOut += t.Accounting.Transmitted()
+	s.Outcomes[t.CacheOutcome]++
+	if class := t.StatusCode / 100; class >= 1 && class <= 5 {
+		s.StatusClasses[class]++
+	} else {
+		s.StatusClasses[0]++
+	}
+	countKey(s.hosts, t.Host)
+	countKey(s.urls, t.Host+t.Url)
+
+	if len(t.Timestamps) > 0 {
+		series := HistogramMisses
+		if t.CacheOutcome == vsl.OutcomeHit {
+			series = HistogramHits
+		}
+		s.Histogram.Add(series, util.DurationValue(t.SumOfSinceLast()))
+	}
+
+	start := t.StartTime()
+	if start.IsZero() {
+		return
+	}
+	if s.First.IsZero() || start.Before(s.First) {
+		s.First = start
+	}
+	if start.After(s.Last) {
+		s.Last = start
+	}
+	if s.Last.Sub(start) > rateWindow {
+		return
+	}
+	s.starts[start.Unix()]++
+	for second := range s.starts {
+		if !s.inRateWindow(second) {
+			delete(s.starts, second)
+		}
+	}
+}
+
+// inRateWindow returns true if the second is within the rate window before the last Start
+func (s *Stats) inRateWindow(second int64) bool {
+	return s.Last.Unix()-second <= int64(rateWindow/time.Second)
+}
+
+func countKey(counts map[string]int, key string) {
+	if _, ok := counts[key]; !ok && len(counts) >= maxTrackedKeys {
+		key = StatsOther
+	}
+	counts[key]++
+}
+
+// Rate returns the requests per second of the last seconds of the capture, measured by
+// the Start timestamps so a log read from a file shows its original rate
+func (s *Stats) Rate() float64 {
+	var reqs int
+	for _, count := range s.starts {
+		reqs += count
+	}
+	if reqs == 0 {
+		return 0
+	}
+	window := min(rateWindow, s.Last.Sub(s.First))
+	if window < time.Second {
+		window = time.Second
+	}
+	return float64(reqs) / window.Seconds()
+}
+
+// AverageRate returns the requests per second since the first request
+func (s *Stats) AverageRate() float64 {
+	elapsed := max(s.Last.Sub(s.First), time.Second)
+	return float64(s.Reqs) / elapsed.Seconds()
+}
+
+// HitRatio returns the ratio of hits among the requests with a known cache outcome
+func (s *Stats) HitRatio() float64 {
+	var total int
+	for outcome, count := range s.Outcomes {
+		if outcome != vsl.OutcomeUnknown {
+			total += count
+		}
+	}
+	if total == 0 {
+		return 0
+	}
+	return float64(s.Outcomes[vsl.OutcomeHit]) / float64(total)
+}
+
+// TopHosts returns the n hosts with more requests
+func (s *Stats) TopHosts(n int) []Counter {
+	return topCounters(s.hosts, n)
+}
+
+// TopURLs returns the n URLs, prefixed by their host, with more requests
+func (s *Stats) TopURLs(n int) []Counter {
+	return topCounters(s.urls, n)
+}
+
+func topCounters(counts map[string]int, n int) []Counter {
+	counters := make([]Counter, 0, len(counts))
+	for k, c := range counts {
+		counters = append(counters, Counter{Key: k, Count: c})
+	}
+	slices.SortFunc(counters, func(a, b Counter) int {
+		return cmp.Or(cmp.Compare(b.Count, a.Count), cmp.Compare(a.Key, b.Key))
+	})
+	return counters[:min(n, len(counters))]
+}
@@ -0,0 +1,74 @@
+package tx
+
+import (
+	"testing"
+	"time"
+
+	"github.com/aorith/varnishlog-tui/pkg/vsl"
+)
+
+// TestStats tests the counters, the rate and the top hosts of the requests
+func TestStats(t *testing.T) {
+	start := time.Date(2024, 6, 1, 10, 0, 0, 0, time.UTC)
+	s := NewStats()
+
+	reqs := []struct {
+		host    string
+		status  int
+		outcome vsl.CacheOutcome
+		offset  time.Duration
+	}{
+		{"www.example.com", 200, vsl.OutcomeHit, 0},
+		{"www.example.com", 200, vsl.OutcomeHit, 5 * time.Second},
+		{"api.example.com", 503, vsl.OutcomeMiss, 15 * time.Second},
+		{"www.example.com", 404, vsl.OutcomePass, 20 * time.Second},
+	}
+	for i, r := range reqs {
+		req := newTestTx("1", uint64(i))
+		req.RecordType = "req"
+		req.Host = r.host
+		req.StatusCode = r.status
+		req.CacheOutcome = r.outcome
+		req.Accounting.BodyBytesTransmitted = 100
+		req.Timestamps = []vsl.Timestamp{{EventLabel: "Start", Absolute: start.Add(r.offset)}}
+		s.Add(&req)
+	}
+	bereq := newTestTx("2", 2)
+	bereq.RecordType = "bereq"
+	bereq.StatusCode = 503
+	s.Add(&bereq)
+
+	if s.Reqs != 4 || s.Bereqs != 1 || s.BackendErrors != 1 {
+		t.Errorf("counts: expected 4 reqs, 1 bereq and 1 backend error, got %d, %d and %d", s.Reqs, s.Bereqs, s.BackendErrors)
+	}
+	if s.StatusClasses[2] != 2 || s.StatusClasses[4] != 1 || s.StatusClasses[5] != 1 {
+		t.Errorf("status classes: expected 2xx=2 4xx=1 5xx=1, got %v", s.StatusClasses)
+	}
+	if s.ClientOut != 400 {
+		t.Errorf("client out: expected 400, got %d", s.ClientOut)
+	}
+	if got := s.HitRatio(); got != 0.5 {
+		t.Errorf("hit ratio: expected 0.5, got %v", got)
+	}
+	// Only the reqs of the last 10 seconds count for the current rate
+	if got := s.Rate(); got != 0.2 {
+		t.Errorf("rate: expected 0.2, got %v", got)
+	}
+	if got := s.AverageRate(); got != 0.2 {
+		t.Errorf("average rate: expected 0.2, got %v", got)
+	}
+
+	// A slow req that started before the window arrives after the faster ones
+	late := newTestTx("3", 3)
+	late.RecordType = "req"
+	late.Timestamps = []vsl.Timestamp{{EventLabel: "Start", Absolute: start.Add(2 * time.Second)}}
+	s.Add(&late)
+	if got := s.Rate(); got != 0.2 {
+		t.Errorf("rate after a late req: expected 0.2, got %v", got)
+	}
+
+	top := s.TopHosts(1)
+	if len(top) != 1 || top[0].Key != "www.example.com" || top[0].Count != 3 {
+		t.Errorf("top hosts: expected www.example.com with 3, got %v", top)
+	}
+}
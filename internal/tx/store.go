@@ -0,0 +1,314 @@
+package tx
+
+import (
+	"slices"
+	"time"
+)
+
+// Retention limits the txs kept in memory, zero values mean no limit.
+// When a limit is exceeded the oldest tx is evicted along with its whole group.
+type Retention struct {
+	MaxTxs   int
+	MaxBytes int64         // Size of the raw log of the txs
+	MaxAge   time.Duration // Time since the tx was received
+	SpillDir string        // Directory where the raw log of evicted txs is written, disabled if empty
+
+	MaxSpillBytes int64 // Disk used by the spilled txs, the oldest segments are deleted
+}
+
+// Store indexes the received txs and links them with their parents, children and hit origins
+// as they arrive, so adding a tx does not require walking the whole buffer
+type Store struct {
+	txs      map[string]*Tx
+	byVxid   map[uint64]*Tx
+	sorted   []*Tx // Sorted by sortMode
+	sortMode SortMode
+	seq      uint64
+
+	// Txs referenced before being received
+	pendingChildren map[string]*Tx   // Child txid -> parent waiting for it
+	pendingHits     map[uint64][]*Tx // Hit origin vxid -> txs that hit its object
+
+	hits map[uint64][]*Tx // Hit origin vxid -> txs linked to it, unlinked when it is evicted
+
+	retention Retention
+	arrivals  []arrival // FIFO of received txs, arrivals[head] is the oldest
+	head      int
+	bytes     int64
+	evicted   int
+	spill     *Spill
+}
+
+// arrival is a received tx, entries of evicted or replaced txs are skipped
+type arrival struct {
+	tx *Tx
+	at time.Time
+}
+
+func NewStore(retention Retention) *Store {
+	return &Store{
+		txs:             make(map[string]*Tx),
+		byVxid:          make(map[uint64]*Tx),
+		pendingChildren: make(map[string]*Tx),
+		pendingHits:     make(map[uint64][]*Tx),
+		hits:            make(map[uint64][]*Tx),
+		retention:       retention,
+	}
+}
+
+// Add stores the tx, links it with the txs already received and returns it
+// along with its index in the sorted txs. replaced is true when a tx with
+// the same Txid was already stored, in that case the old one is removed first
+// and the indexes of the txs after it are shifted.
+func (s *Store) Add(newTx Tx) (t *Tx, index int, replaced bool) {
+	t = &newTx
+	s.seq++
+	t.seq = s.seq
+
+	if old, ok := s.txs[t.Txid]; ok {
+		replaced = true
+		if old.Parent != nil {
+			link(old.Parent, t)
+		}
+		s.bytes -= rawSize(old)
+		s.deleteSorted(old)
+		s.unlinkHit(old)
+	}
+	index, _ = slices.BinarySearchFunc(s.sorted, t, s.sortMode.compare)
+	s.sorted = slices.Insert(s.sorted, index, t)
+	s.txs[t.Txid] = t
+	if t.Vxid != 0 {
+		s.byVxid[t.Vxid] = t
+	}
+	s.bytes += rawSize(t)
+	s.arrivals = append(s.arrivals, arrival{tx: t, at: time.Now()})
+
+	// Children
+	for childId := range t.Children {
+		if child, ok := s.txs[childId]; ok {
+			link(t, child)
+		} else {
+			s.pendingChildren[childId] = t
+		}
+	}
+	if parent, ok := s.pendingChildren[t.Txid]; ok {
+		delete(s.pendingChildren, t.Txid)
+		link(parent, t)
+	}
+
+	// Hit origins
+	if t.HitVxid != 0 {
+		if origin, ok := s.byVxid[t.HitVxid]; ok {
+			t.HitTx = origin
+			s.hits[t.HitVxid] = append(s.hits[t.HitVxid], t)
+		} else {
+			s.pendingHits[t.HitVxid] = append(s.pendingHits[t.HitVxid], t)
+		}
+	}
+	if t.Vxid != 0 {
+		s.hits[t.Vxid] = append(s.hits[t.Vxid], s.pendingHits[t.Vxid]...)
+		delete(s.pendingHits, t.Vxid)
+		// Including the hits of the tx it replaces
+		for _, hit := range s.hits[t.Vxid] {
+			hit.HitTx = t
+		}
+	}
+
+	return t, index, replaced
+}
+
+// Get returns the tx with the given Txid or nil if it was not received
+func (s *Store) Get(txid string) *Tx {
+	return s.txs[txid]
+}
+
+// GetByVxid returns the tx with the given vxid or nil if it was not received
+func (s *Store) GetByVxid(vxid uint64) *Tx {
+	return s.byVxid[vxid]
+}
+
+// Len returns the number of stored txs
+func (s *Store) Len() int {
+	return len(s.sorted)
+}
+
+// Evicted returns the number of txs evicted by the retention limits
+func (s *Store) Evicted() int {
+	return s.evicted
+}
+
+// Spill returns the spill of the evicted txs, nil if nothing was spilled
+func (s *Store) Spill() *Spill {
+	return s.spill
+}
+
+// Evict removes the oldest groups of txs until the retention limits are met and
+// returns the evicted txs. They are spilled first if enabled, a spill error
+// is returned but the txs are evicted anyway to keep the memory bounded.
+func (s *Store) Evict(now time.Time) (evicted []*Tx, err error) {
+	for s.overLimit(now) {
+		a := s.arrivals[s.head]
+		s.head++
+
+		group := s.group(a.tx)
+		if s.retention.SpillDir != "" && err == nil {
+			err = s.spillGroup(group)
+		}
+		for _, t := range group {
+			s.remove(t)
+		}
+		evicted = append(evicted, group...)
+	}
+	if err != nil {
+		s.retention.SpillDir = ""
+	}
+
+	// Compact the FIFO once most of it has been consumed
+	if s.head > len(s.arrivals)/2 {
+		s.arrivals = slices.Clone(s.arrivals[s.head:])
+		s.head = 0
+	}
+	return evicted, err
+}
+
+// overLimit skips the arrivals of txs that are no longer stored and
+// returns true if the oldest one has to be evicted
+func (s *Store) overLimit(now time.Time) bool {
+	for s.head < len(s.arrivals) && s.txs[s.arrivals[s.head].tx.Txid] != s.arrivals[s.head].tx {
+		s.head++
+	}
+	if s.head == len(s.arrivals) {
+		return false
+	}
+
+	r := s.retention
+	return (r.MaxTxs > 0 && len(s.sorted) > r.MaxTxs) ||
+		(r.MaxBytes > 0 && s.bytes > r.MaxBytes) ||
+		(r.MaxAge > 0 && now.Sub(s.arrivals[s.head].at) > r.MaxAge)
+}
+
+// group returns the stored txs of the group of t: its root parent and all the descendants
+func (s *Store) group(t *Tx) []*Tx {
+	root := t
+	for root.Parent != nil {
+		root = root.Parent
+	}
+
+	group := []*Tx{root}
+	for _, child := range root.GetSortedChildren() {
+		if s.txs[child.Txid] == child {
+			group = append(group, child)
+		}
+	}
+	return group
+}
+
+func (s *Store) spillGroup(group []*Tx) error {
+	if s.spill == nil {
+		spill, err := NewSpill(s.retention.SpillDir, s.retention.MaxSpillBytes)
+		if err != nil {
+			return err
+		}
+		s.spill = spill
+	}
+	return s.spill.Write(group)
+}
+
+// remove deletes the tx from the indexes, the arrival entry is skipped later
+func (s *Store) remove(t *Tx) {
+	if s.txs[t.Txid] != t {
+		return
+	}
+	delete(s.txs, t.Txid)
+	if s.byVxid[t.Vxid] == t {
+		delete(s.byVxid, t.Vxid)
+	}
+	s.deleteSorted(t)
+
+	// Children received later start a new group instead of linking to an evicted parent
+	for childId := range t.Children {
+		if s.pendingChildren[childId] == t {
+			delete(s.pendingChildren, childId)
+		}
+	}
+	s.unlinkHit(t)
+
+	// Hits of the tx wait for it again, so they don't keep its group in memory
+	if t.Vxid != 0 {
+		for _, hit := range s.hits[t.Vxid] {
+			hit.HitTx = nil
+		}
+		s.pendingHits[t.Vxid] = append(s.pendingHits[t.Vxid], s.hits[t.Vxid]...)
+		delete(s.hits, t.Vxid)
+	}
+
+	s.bytes -= rawSize(t)
+	s.evicted++
+}
+
+// unlinkHit removes the tx from the hits of its origin, linked or pending
+func (s *Store) unlinkHit(t *Tx) {
+	if t.HitVxid == 0 {
+		return
+	}
+	hits := s.pendingHits
+	if t.HitTx != nil {
+		hits = s.hits
+	}
+	hits[t.HitVxid] = slices.DeleteFunc(hits[t.HitVxid], func(e *Tx) bool { return e == t })
+	if len(hits[t.HitVxid]) == 0 {
+		delete(hits, t.HitVxid)
+	}
+}
+
+// Sorted returns the stored txs in the current sort mode, the slice must not be modified
+func (s *Store) Sorted() []*Tx {
+	return s.sorted
+}
+
+// SortMode returns the current sort mode
+func (s *Store) SortMode() SortMode {
+	return s.sortMode
+}
+
+// SetSortMode sorts the stored txs by the given mode, the following ones are inserted in order
+func (s *Store) SetSortMode(mode SortMode) {
+	s.sortMode = mode
+	slices.SortFunc(s.sorted, mode.compare)
+}
+
+// Compare orders two txs like the sorted txs, so lists of a subset of them can be kept in order
+func (s *Store) Compare(a, b *Tx) int {
+	return s.sortMode.compare(a, b)
+}
+
+func (s *Store) deleteSorted(t *Tx) {
+	if index, found := slices.BinarySearchFunc(s.sorted, t, s.sortMode.compare); found {
+		s.sorted = slices.Delete(s.sorted, index, index+1)
+	}
+}
+
+// rawSize returns the size in bytes of the raw log of the tx
+func rawSize(t *Tx) (size int64) {
+	for _, line := range t.RawTx {
+		size += int64(len(line)) + 1
+	}
+	return size
+}
+
+// link sets the parent of the child and propagates the client of the parent
+// to the child and the descendants it already has
+func link(parent, child *Tx) {
+	child.Parent = parent
+	parent.Children[child.Txid] = child
+	inheritClient(parent, child)
+}
+
+func inheritClient(parent, child *Tx) {
+	child.InheritClient(&parent.Tx)
+	for _, grandchild := range child.Children {
+		if grandchild.Parent == child {
+			inheritClient(child, grandchild)
+		}
+	}
+}
@@ -0,0 +1,216 @@
+package tx
+
+import (
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aorith/varnishlog-tui/pkg/vsl"
+)
+
+func newTestTx(txid string, vxid uint64, links ...string) Tx {
+	v := vsl.Tx{Txid: txid, Vxid: vxid}
+	for _, l := range links {
+		v.Links = append(v.Links, vsl.Link{Txid: l})
+	}
+	return New(v)
+}
+
+// TestStoreLinksOutOfOrder tests that relationships are linked regardless of the arrival order
+func TestStoreLinksOutOfOrder(t *testing.T) {
+	s := NewStore(Retention{})
+
+	// -g request delivers the bereq and the req before the session
+	bereq := newTestTx("3", 3)
+	s.Add(bereq)
+
+	req := newTestTx("2", 2, "3")
+	s.Add(req)
+
+	hit := newTestTx("5", 5)
+	hit.HitVxid = 4
+	s.Add(hit)
+
+	sess := newTestTx("1", 1, "2")
+	sess.Client.IP = "192.168.50.1"
+	s.Add(sess)
+
+	origin := newTestTx("4", 4)
+	s.Add(origin)
+
+	if got := s.Get("3").Parent; got != s.Get("2") {
+		t.Errorf("expected the bereq parent to be the req, got %v", got)
+	}
+	if got := s.Get("2").Parent; got != s.Get("1") {
+		t.Errorf("expected the req parent to be the session, got %v", got)
+	}
+	if got := s.Get("1").Children["2"]; got != s.Get("2") {
+		t.Errorf("expected the session child to be the stored req, got %v", got)
+	}
+	if got := s.Get("3").Client.IP; got != "192.168.50.1" {
+		t.Errorf("expected the bereq to inherit the client of the session, got %q", got)
+	}
+	if got := s.Get("5").HitTx; got != s.GetByVxid(4) {
+		t.Errorf("expected the hit origin to be linked once received, got %v", got)
+	}
+}
+
+// TestStoreSortedInsertion tests that Add returns the index of the tx in the current sort mode
+func TestStoreSortedInsertion(t *testing.T) {
+	s := NewStore(Retention{})
+	s.SetSortMode(SortByVxid)
+
+	tests := []struct {
+		vxid     uint64
+		index    int
+		replaced bool
+	}{
+		{20, 0, false},
+		{9, 0, false},
+		{30, 2, false},
+		{15, 1, false},
+		{20, 2, true},
+	}
+
+	for _, tt := range tests {
+		txid := strconv.FormatUint(tt.vxid, 10)
+		_, index, replaced := s.Add(newTestTx(txid, tt.vxid))
+		if index != tt.index || replaced != tt.replaced {
+			t.Errorf("Add(%s): expected (%d, %t), got (%d, %t)", txid, tt.index, tt.replaced, index, replaced)
+		}
+	}
+
+	assertOrder := func(expected []string) {
+		t.Helper()
+		var got []string
+		for _, t := range s.Sorted() {
+			got = append(got, t.Txid)
+		}
+		if !slices.Equal(got, expected) || s.Len() != len(expected) {
+			t.Errorf("%s: expected %v, got %v", s.SortMode(), expected, got)
+		}
+	}
+	assertOrder([]string{"9", "15", "20", "30"})
+
+	// The replaced tx is received again
+	s.SetSortMode(SortByArrival)
+	assertOrder([]string{"9", "30", "15", "20"})
+}
+
+// TestStoreEvictsWholeGroups tests that the retention limits evict the oldest groups and spill them
+func TestStoreEvictsWholeGroups(t *testing.T) {
+	s := NewStore(Retention{MaxTxs: 3, SpillDir: t.TempDir()})
+
+	for _, newTx := range []Tx{
+		newTestTx("2", 2, "3"),
+		newTestTx("3", 3),
+		newTestTx("1", 1, "2"),
+		newTestTx("4", 4),
+		newTestTx("5", 5),
+	} {
+		newTx.RawTx = []string{"*   << Request  >> " + newTx.Txid, "-   End"}
+		s.Add(newTx)
+	}
+
+	evicted, err := s.Evict(time.Now())
+	if err != nil {
+		t.Fatalf("Evict returned an error: %s", err)
+	}
+
+	// The oldest tx belongs to the group of session 1, it leaves with its children
+	if len(evicted) != 3 || s.Len() != 2 || s.Evicted() != 3 {
+		t.Fatalf("expected 3 evicted and 2 stored txs, got %d evicted and %d stored", len(evicted), s.Len())
+	}
+	for _, txid := range []string{"1", "2", "3"} {
+		if s.Get(txid) != nil {
+			t.Errorf("expected tx %s to be evicted", txid)
+		}
+	}
+	if !s.Spill().Contains(2) || s.Spill().Contains(5) || s.Spill().Len() != 3 {
+		t.Errorf("expected the txs 1 to 3 to be spilled, got %d", s.Spill().Len())
+	}
+
+	segments, err := s.Spill().Seal()
+	if err != nil || len(segments) != 1 {
+		t.Fatalf("expected 1 segment, got %v (%v)", segments, err)
+	}
+	raw, err := os.ReadFile(segments[0])
+	if err != nil {
+		t.Fatalf("ReadFile returned an error: %s", err)
+	}
+	if !strings.Contains(string(raw), "*   << Request  >> 2\n-   End\n\n") {
+		t.Errorf("unexpected spilled raw log: %q", raw)
+	}
+}
+
+// TestStoreEvictionUnlinksHits tests that the hits of an evicted origin don't keep it in memory
+// and are linked again if it is received again
+func TestStoreEvictionUnlinksHits(t *testing.T) {
+	s := NewStore(Retention{MaxTxs: 2})
+
+	s.Add(newTestTx("4", 4))
+	hit := newTestTx("5", 5)
+	hit.HitVxid = 4
+	s.Add(hit)
+	if got := s.Get("5").HitTx; got != s.Get("4") {
+		t.Fatalf("expected the hit origin to be linked, got %v", got)
+	}
+
+	s.Add(newTestTx("6", 6))
+	if _, err := s.Evict(time.Now()); err != nil {
+		t.Fatalf("Evict returned an error: %s", err)
+	}
+	if s.Get("4") != nil {
+		t.Fatalf("expected the origin to be evicted")
+	}
+	if got := s.Get("5").HitTx; got != nil {
+		t.Errorf("expected the hit to no longer reference the evicted origin, got %v", got)
+	}
+
+	s.Add(newTestTx("4", 4))
+	if got := s.Get("5").HitTx; got != s.Get("4") {
+		t.Errorf("expected the hit origin to be linked again, got %v", got)
+	}
+}
+
+// TestSpillDeletesOldSegments tests that the segments are kept within the size limit
+func TestSpillDeletesOldSegments(t *testing.T) {
+	dir := t.TempDir()
+	spill, err := NewSpill(dir, 4096)
+	if err != nil {
+		t.Fatalf("NewSpill returned an error: %s", err)
+	}
+
+	for i := uint64(1); i <= 100; i++ {
+		newTx := newTestTx(strconv.FormatUint(i, 10), i)
+		newTx.RawTx = []string{"*   << Request  >> " + newTx.Txid, strings.Repeat("-   ReqURL /", 20), "-   End"}
+		if err := spill.Write([]*Tx{&newTx}); err != nil {
+			t.Fatalf("Write returned an error: %s", err)
+		}
+	}
+	if err := spill.Close(); err != nil {
+		t.Fatalf("Close returned an error: %s", err)
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatalf("ReadDir returned an error: %s", err)
+	}
+	var size int64
+	for _, e := range entries {
+		info, _ := e.Info()
+		size += info.Size()
+	}
+	if size > 4096 {
+		t.Errorf("expected the segments to take at most 4096 bytes, got %d in %d files", size, len(entries))
+	}
+	if spill.Contains(1) || !spill.Contains(100) {
+		t.Errorf("expected only the latest txs to be spilled")
+	}
+	if spill.Len() >= 100 || spill.Len() == 0 {
+		t.Errorf("expected some txs to be deleted, got %d", spill.Len())
+	}
+}
@@ -0,0 +1,159 @@
+package tx
+
+import (
+	"cmp"
+	"fmt"
+	"math"
+	"slices"
+	"strings"
+	"time"
+)
+
+// TopFields are the fields offered to rank, any field of the filter can be ranked
+var TopFields = []string{"url", "host", "client", "req.User-Agent", "status", "backend", "method", "outcome"}
+
+// topLiveLag is how far the Start timestamps of a live capture can be from the wall clock
+const topLiveLag = time.Minute
+
+// Top ranks the values of a field of the reqs, or of the bereqs for the backend fields,
+// like varnishtop does. The counts decay exponentially, so a value not seen for a window
+// weighs about a third of what it did. The time is measured by the Start timestamps of
+// the txs, so logs read from a file keep their pace, and keeps running with the wall
+// clock after the last tx of a live capture.
+type Top struct {
+	field      string
+	recordType string // Type of the txs counted
+	values     func(t *Tx) []string
+	window     time.Duration
+	now        time.Time // Latest Start timestamp counted
+	seen       time.Time // Wall clock when now was counted
+	live       bool      // The latest Start timestamp was close to the wall clock
+	wallNow    func() time.Time
+	entries    map[string]*topEntry
+}
+
+type topEntry struct {
+	count float64
+	last  time.Time
+}
+
+// TopEntry is a ranked value and its decayed count
+type TopEntry struct {
+	Key   string
+	Count float64
+}
+
+// NewTop returns a ranking of the values of a filter field
+func NewTop(field string, window time.Duration) (*Top, error) {
+	f, ok := lookupFilterField(field)
+	if !ok {
+		return nil, fmt.Errorf("unknown field %q, use one of: %s", field, strings.Join(FilterFields, " "))
+	}
+	recordType := "req"
+	if lower := strings.ToLower(field); lower == "backend" || strings.HasPrefix(lower, "bereq.") || strings.HasPrefix(lower, "beresp.") {
+		recordType = "bereq"
+	}
+	return &Top{
+		field:      field,
+		recordType: recordType,
+		values:     f.strings,
+		window:     window,
+		wallNow:    time.Now,
+		entries:    make(map[string]*topEntry),
+	}, nil
+}
+
+// Field returns the field being ranked
+func (tp *Top) Field() string {
+	return tp.field
+}
+
+// Window returns the period in which the counts decay
+func (tp *Top) Window() time.Duration {
+	return tp.window
+}
+
+// Add counts the values of the field of the tx, txs without a value are ignored
+func (tp *Top) Add(t *Tx) {
+	if t.RecordType != tp.recordType {
+		return
+	}
+	values := tp.values(t)
+	if len(values) == 0 {
+		return
+	}
+
+	now := t.StartTime()
+	if now.IsZero() || now.Before(tp.now) {
+		now = tp.now
+	} else {
+		wall := tp.wallNow()
+		lag := wall.Sub(now)
+		tp.now, tp.seen, tp.live = now, wall, lag < topLiveLag && lag > -topLiveLag
+	}
+	now = tp.clock()
+
+	for _, v := range values {
+		if v == "" {
+			continue
+		}
+		e, ok := tp.entries[v]
+		if !ok {
+			e = &topEntry{last: now}
+			tp.entries[v] = e
+		}
+		e.count = tp.decay(e, now) + 1
+		e.last = now
+	}
+
+	if len(tp.entries) > maxTrackedKeys {
+		tp.prune()
+	}
+}
+
+// clock returns the current time of the ranking, the latest Start timestamp plus
+// the wall time elapsed since it was counted in live captures
+func (tp *Top) clock() time.Time {
+	if !tp.live {
+		return tp.now
+	}
+	return tp.now.Add(tp.wallNow().Sub(tp.seen))
+}
+
+// decay returns the count of the entry at now
+func (tp *Top) decay(e *topEntry, now time.Time) float64 {
+	if tp.window <= 0 || !now.After(e.last) {
+		return e.count
+	}
+	return e.count * math.Exp(-float64(now.Sub(e.last))/float64(tp.window))
+}
+
+// prune keeps the three quarters of the maximum of entries with the highest counts
+func (tp *Top) prune() {
+	ranking := tp.Ranking(len(tp.entries))
+	for _, e := range ranking[maxTrackedKeys*3/4:] {
+		delete(tp.entries, e.Key)
+	}
+}
+
+// Ranking returns the n values with the highest decayed counts
+func (tp *Top) Ranking(n int) []TopEntry {
+	now := tp.clock()
+	ranking := make([]TopEntry, 0, len(tp.entries))
+	for k, e := range tp.entries {
+		ranking = append(ranking, TopEntry{Key: k, Count: tp.decay(e, now)})
+	}
+	slices.SortFunc(ranking, func(a, b TopEntry) int {
+		return cmp.Or(cmp.Compare(b.Count, a.Count), strings.Compare(a.Key, b.Key))
+	})
+	return ranking[:min(n, len(ranking))]
+}
+
+// FilterExpr returns the filter expression that selects the txs with the value
+//
+//	url="/api/items?q=\"x\""
+func (tp *Top) FilterExpr(value string) string {
+	value = strings.ReplaceAll(value, `\`, `\\`)
+	value = strings.ReplaceAll(value, `"`, `\"`)
+	return fmt.Sprintf(`%s="%s"`, tp.field, value)
+}
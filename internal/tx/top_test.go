@@ -0,0 +1,88 @@
+package tx
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/aorith/varnishlog-tui/pkg/vsl"
+)
+
+// TestTop tests the ranking of the decayed counts and the filter of a value
+func TestTop(t *testing.T) {
+	start := time.Date(2024, 6, 1, 10, 0, 0, 0, time.UTC)
+	top, err := NewTop("url", time.Minute)
+	if err != nil {
+		t.Fatalf("NewTop returned an error: %s", err)
+	}
+
+	add := func(url string, offset time.Duration) {
+		req := newTestTx("1", 1)
+		req.RecordType = "req"
+		req.Url = url
+		req.Timestamps = []vsl.Timestamp{{EventLabel: "Start", Absolute: start.Add(offset)}}
+		top.Add(&req)
+	}
+
+	// An old burst of /old decays below the recent /new requests
+	for i := 0; i < 10; i++ {
+		add("/old", 0)
+	}
+	for i := 0; i < 5; i++ {
+		add("/new", 2*time.Minute)
+	}
+	add("", 2*time.Minute)
+	bereq := newTestTx("3", 3)
+	bereq.RecordType = "bereq"
+	bereq.Url = "/new"
+	top.Add(&bereq)
+
+	ranking := top.Ranking(10)
+	if len(ranking) != 2 {
+		t.Fatalf("expected 2 values, got %v", ranking)
+	}
+	if ranking[0].Key != "/new" || ranking[0].Count != 5 {
+		t.Errorf("first: expected /new with 5, got %v", ranking[0])
+	}
+	if expected := 10 * math.Exp(-2); ranking[1].Key != "/old" || math.Abs(ranking[1].Count-expected) > 1e-9 {
+		t.Errorf("second: expected /old with %v, got %v", expected, ranking[1])
+	}
+
+	filter, err := ParseFilter(top.FilterExpr(`/a "quoted" \ value`))
+	if err != nil {
+		t.Fatalf("the filter of a value could not be parsed: %s", err)
+	}
+	req := newTestTx("2", 2)
+	req.Url = `/a "quoted" \ value`
+	if !filter.Match(&req) {
+		t.Errorf("expected %s to match the url %s", filter, req.Url)
+	}
+
+	if _, err := NewTop("nonexistent", time.Minute); err == nil {
+		t.Errorf("expected an error for an unknown field")
+	}
+}
+
+// TestTopLive tests that the counts of a live capture keep decaying while no txs arrive
+func TestTopLive(t *testing.T) {
+	wall := time.Date(2024, 6, 1, 10, 0, 0, 0, time.UTC)
+	top, err := NewTop("url", time.Minute)
+	if err != nil {
+		t.Fatalf("NewTop returned an error: %s", err)
+	}
+	top.wallNow = func() time.Time { return wall }
+
+	req := newTestTx("1", 1)
+	req.RecordType = "req"
+	req.Url = "/live"
+	req.Timestamps = []vsl.Timestamp{{EventLabel: "Start", Absolute: wall.Add(-time.Second)}}
+	for i := 0; i < 10; i++ {
+		top.Add(&req)
+	}
+
+	wall = wall.Add(time.Minute)
+	ranking := top.Ranking(10)
+	if expected := 10 * math.Exp(-1); len(ranking) != 1 || math.Abs(ranking[0].Count-expected) > 1e-9 {
+		t.Errorf("expected /live with %v, got %v", expected, ranking)
+	}
+}
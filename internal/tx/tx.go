@@ -1,71 +1,73 @@
 package tx
 
 import (
+	"cmp"
 	"fmt"
+	"slices"
 	"sort"
 	"strings"
 	"time"
 
 	"github.com/aorith/varnishlog-tui/internal/ui/styles"
 	"github.com/aorith/varnishlog-tui/internal/util"
+	"github.com/aorith/varnishlog-tui/pkg/vsl"
 	"github.com/charmbracelet/lipgloss"
 )
 
-// Tx represents a single transaction
+// maxDisplayLen is the maximum length of the values shown and filtered in the list,
+// huge URLs or headers are kept complete for the reports
+const maxDisplayLen = 256
+
+// Tx represents a single transaction along with its related transactions
 type Tx struct {
-	Txid         string
-	Vxid         uint64
-	RecordType   string // req, bereq, sess
-	Reason       string // rxreq, fetch, esi, ...
-	Method       string
-	Host         string
-	Url          string
-	StatusCode   int
-	StatusReason string
-	Timestamps   []Timestamp
-	Transitions  []VCLTransition
-	TTL          []TTLData
-	Accounting   RequestAccounting
-	Parent       *Tx
-	Children     map[string]*Tx
-	RawTx        []string
-}
+	vsl.Tx
+	HitTx    *Tx // bereq that stored the object served on hits, if received
+	Parent   *Tx
+	Children map[string]*Tx
 
-type Timestamp struct {
-	EventLabel string        // Start, Req, Fetch, Process, Resp, ...
-	Absolute   time.Time     // Absolute time of the timestamp
-	SinceStart time.Duration // Duration since the start of the tx
-	SinceLast  time.Duration // Duration since the last timestamp
+	seq uint64 // Arrival order in the Store
 }
 
-type TTLData struct {
-	Source      string    // "RFC", "VCL" or "HFP"
-	TTL         int       // Time-to-live
-	Grace       int       // Grace period
-	Keep        int       // Keep period
-	Reference   time.Time // Reference time for TTL
-	Age         int       // Age (incl Age: header value)
-	Date        time.Time // Date header
-	Expires     time.Time // Expires header
-	MaxAge      int       // Max-Age from Cache-Control header
-	CacheStatus string    // "cacheable" or "uncacheable"
+// New creates a Tx from a parsed vsl.Tx, its children are placeholders with the
+// details of the Link record until they are received and linked by the Store
+func New(v vsl.Tx) Tx {
+	t := Tx{
+		Tx:       v,
+		Children: make(map[string]*Tx),
+	}
+	for _, link := range v.Links {
+		t.Children[link.Txid] = &Tx{Tx: vsl.Tx{
+			Txid:       link.Txid,
+			Vxid:       link.Vxid,
+			RecordType: link.RecordType,
+			Reason:     link.Reason,
+		}}
+	}
+	return t
 }
 
-type VCLTransition struct {
-	Call   string // RECV, HASH
-	Return string // synth, lookup
+// Received returns false for the placeholders of linked children that were never received
+func (t *Tx) Received() bool {
+	return len(t.RawTx) > 0
 }
 
-type RequestAccounting struct {
-	HeaderBytesReceived    util.SizeValue
-	BodyBytesReceived      util.SizeValue
-	HeaderBytesTransmitted util.SizeValue
-	BodyBytesTransmitted   util.SizeValue
+// DirectChildren returns the children of the tx sorted by vxid, including placeholders
+func (t *Tx) DirectChildren() []*Tx {
+	children := make([]*Tx, 0, len(t.Children))
+	for _, child := range t.Children {
+		if child != nil {
+			children = append(children, child)
+		}
+	}
+	slices.SortFunc(children, func(a, b *Tx) int {
+		return cmp.Or(cmp.Compare(a.Vxid, b.Vxid), strings.Compare(a.Txid, b.Txid))
+	})
+	return children
 }
 
 // FilterValue satisfaces list.Item interface
 func (t Tx) FilterValue() string {
-	return strings.ReplaceAll(t.AsString(nil, false, false), "\n", " ")
+	return strings.ReplaceAll(t.AsString(nil, false, false), "\n", " ") + " " + t.detailsLine()
 }
 
 // AsString
@@ -78,9 +80,9 @@ func (t Tx) AsString(matchedRunes []int, highlight, highlightMatches bool) strin
 		recordType   string = t.RecordType
 		parentId     string = "-"
 		reason       string = t.Reason
-		host         string = t.Host
+		host         string = util.TruncateString(t.Host, maxDisplayLen)
 		method       string = t.Method
-		url          string = t.Url
+		url          string = util.TruncateString(t.Url, maxDisplayLen)
 		statusCode   string = fmt.Sprintf("(%d", t.StatusCode)
 		statusReason string = t.StatusReason + ")"
 		offset       int    = 0
@@ -91,7 +93,10 @@ func (t Tx) AsString(matchedRunes []int, highlight, highlightMatches bool) strin
 
 	if t.RecordType == "sess" {
 		// In sessions the Host is either empty or an store overflow
-		// method is always "-" and Url is SessionOpen
+		// method is always "-" and Url is the client address
+		if addr := t.Client.Addr(); addr != "" {
+			url = util.TruncateString(fmt.Sprintf("SessOpen %s via %s to %s", addr, t.Client.Listener, t.Client.LocalAddr), maxDisplayLen)
+		}
 		statusCode = ""
 		statusReason = ""
 		if host != "-" && host != "" {
@@ -144,10 +149,12 @@ func (t Tx) AsString(matchedRunes []int, highlight, highlightMatches bool) strin
 //	123 req 122 rxreq (200 OK)
 //	GET www.example.com/path/to/asset
 //	178µs total for Start(0s) → Fetch(140µs) → Process(6µs) → Resp(32µs)
+//	client 192.168.50.1:50312 via a0 HTTP/1.1 xff: 192.168.50.1
 func (t Tx) AsItem(matchedRunes []int, highlight, highlightMatches bool) string {
 	var (
 		tsFlow     string
 		tsTotalDur time.Duration = 0
+		details    string        = t.detailsLine()
 	)
 
 	for _, ts := range t.Timestamps {
@@ -157,15 +164,33 @@ func (t Tx) AsItem(matchedRunes []int, highlight, highlightMatches bool) string
 
 	if highlight {
 		tsFlow = styles.TsFlowStyle.Render(tsFlow)
+		if len(t.Backend.FetchErrors) > 0 {
+			details = styles.ErrorStyle.Inline(true).Render(details)
+		} else {
+			details = styles.DetailsStyle.Render(details)
+		}
 	}
 
 	return fmt.Sprintf(
-		"%s\n%s",
+		"%s\n%s\n%s",
 		t.AsString(matchedRunes, highlight, highlightMatches),
 		tsFlow,
+		details,
 	)
 }
 
+// detailsLine returns a line with extra details depending on the record type
+func (t Tx) detailsLine() string {
+	switch t.RecordType {
+	case "bereq":
+		return util.TruncateString(t.BackendSummary(), maxDisplayLen)
+	case "req", "sess":
+		return util.TruncateString(t.ClientSummary(), maxDisplayLen)
+	default:
+		return ""
+	}
+}
+
 func styleRunesWithOffset(s string, offset int, matchedRunes []int, style lipgloss.Style) (string, int) {
 	var (
 		length        int = len(s)
@@ -199,13 +224,46 @@ func (t Tx) FindRootParent() *Tx {
 	return t.Parent.FindRootParent()
 }
 
-// SumOfSinceLast returns the sum of all the SinceLast of this Tx Timestamps
-func (t Tx) SumOfSinceLast() time.Duration {
-	var total time.Duration
-	for _, ts := range t.Timestamps {
-		total += ts.SinceLast
+// Group returns the root parent of the tx followed by all its descendants sorted by Txid
+func (t *Tx) Group() []*Tx {
+	root := t
+	for root.Parent != nil {
+		root = root.Parent
+	}
+	return append([]*Tx{root}, root.GetSortedChildren()...)
+}
+
+// MatchQuery returns true if the group the tx was logged in matches the VSL query, like varnishlog -q does
+func (t *Tx) MatchQuery(q *vsl.Query) bool {
+	group := t.QueryGroup()
+	vslGroup := make([]*vsl.Tx, len(group))
+	for i, g := range group {
+		vslGroup[i] = &g.Tx
+	}
+	return q.Match(vslGroup)
+}
+
+// QueryGroup returns the received txs of the group the tx was logged in. With -g request
+// or session its children are nested in it and share the levels of the capture, with
+// -g vxid or raw every tx is logged on its own at level 1.
+func (t *Tx) QueryGroup() []*Tx {
+	root := t
+	for root.Level() > 1 && root.Parent != nil && root.Parent.Received() {
+		root = root.Parent
+	}
+
+	group := []*Tx{root}
+	var collect func(parent *Tx)
+	collect = func(parent *Tx) {
+		for _, child := range parent.Children {
+			if child != nil && child.Parent == parent && child.Received() && child.Level() > 1 {
+				group = append(group, child)
+				collect(child)
+			}
+		}
 	}
-	return total
+	collect(root)
+	return group
 }
 
 // GetSortedChildren retrieves all children and their descendants and returns them sorted by Txid.
@@ -0,0 +1,55 @@
+package tx
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/aorith/varnishlog-tui/pkg/vsl"
+)
+
+// TestMatchQueryGroups tests that VSL queries are evaluated on the group the txs were logged in
+func TestMatchQueryGroups(t *testing.T) {
+	newLoggedTx := func(txid string, vxid uint64, prefix string, lines []string, links ...string) Tx {
+		newTx := newTestTx(txid, vxid, links...)
+		header := "*   << Request  >> "
+		if prefix == "--" {
+			header = "**  << BeReq    >> "
+		}
+		newTx.RawTx = []string{header + txid}
+		for _, line := range lines {
+			newTx.RawTx = append(newTx.RawTx, fmt.Sprintf("%-4s%s", prefix, line))
+		}
+		return newTx
+	}
+
+	tests := []struct {
+		name   string
+		prefix string // Prefix of the records of the bereq
+		query  string
+		req    bool
+		bereq  bool
+	}{
+		{"request", "--", `{2}BerespStatus == 200`, true, true},
+		{"request", "--", `ReqURL ~ "^/a"`, true, true},
+		{"vxid", "-", `{2}BerespStatus == 200`, false, false},
+		{"vxid", "-", `{1}BerespStatus == 200`, false, true},
+		{"vxid", "-", `ReqURL ~ "^/a"`, true, false},
+	}
+
+	for _, tt := range tests {
+		req := newLoggedTx("2", 2, "-", []string{"ReqURL /a"}, "3")
+		bereq := newLoggedTx("3", 3, tt.prefix, []string{"BerespStatus 200"})
+		link(&req, &bereq)
+
+		q, err := vsl.ParseQuery(tt.query)
+		if err != nil {
+			t.Fatalf("ParseQuery(%q) returned an error: %s", tt.query, err)
+		}
+		if got := req.MatchQuery(q); got != tt.req {
+			t.Errorf("%s %q: expected the req to match %t, got %t", tt.name, tt.query, tt.req, got)
+		}
+		if got := bereq.MatchQuery(q); got != tt.bereq {
+			t.Errorf("%s %q: expected the bereq to match %t, got %t", tt.name, tt.query, tt.bereq, got)
+		}
+	}
+}
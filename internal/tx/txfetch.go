@@ -0,0 +1,184 @@
+package tx
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/aorith/varnishlog-tui/internal/ui/state"
+	"github.com/aorith/varnishlog-tui/pkg/vsl"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/log"
+)
+
+// Batches of received txs are sent to the UI at most every txBatchWindow
+// so sustained captures don't trigger an update per tx
+const (
+	MaxTxBatch    = 1000
+	txBatchWindow = 50 * time.Millisecond
+)
+
+// NewTxsMsg is a batch of txs received from Source
+type NewTxsMsg struct {
+	Txs    []Tx
+	Source chan Tx
+}
+
+// TxsClearedMsg is sent when the txs received from Source are discarded
+type TxsClearedMsg struct {
+	Source chan Tx
+}
+
+type FetchEndMsg struct {
+	Err error
+}
+
+// FetchStats holds the parser stats of a running fetch, it is updated by the fetch and read by the UI
+type FetchStats struct {
+	stats atomic.Pointer[vsl.ParserStats]
+}
+
+// Load returns the last stats stored by the fetch
+func (s *FetchStats) Load() vsl.ParserStats {
+	if stats := s.stats.Load(); stats != nil {
+		return *stats
+	}
+	return vsl.ParserStats{}
+}
+
+func (s *FetchStats) store(stats vsl.ParserStats) {
+	s.stats.Store(&stats)
+}
+
+func ExecVarnishlogAndFetchTxs(script state.NewVarnishlogScriptMsg, cancelChan chan struct{}, txChan chan Tx, stats *FetchStats) tea.Cmd {
+	tmpCmdScript, err := os.CreateTemp("", "varnishlog-tui-command-*.sh")
+	if err != nil {
+		return func() tea.Msg {
+			return FetchEndMsg{Err: fmt.Errorf("Error creating temporary command file: %s", err.Error())}
+		}
+	}
+	tmpCmdScriptName := tmpCmdScript.Name()
+	go func() {
+		time.Sleep(time.Second * 1) // Give some time until the script is executed
+		if err := os.Remove(tmpCmdScriptName); err != nil {
+			log.Debug(fmt.Sprintf("Error removing temporary file: %s", err.Error()))
+		}
+	}()
+
+	return func() tea.Msg {
+		defer close(txChan)
+
+		cmdString := fmt.Sprintf("exec %s", script)
+		if _, err := tmpCmdScript.Write([]byte(cmdString)); err != nil {
+			return FetchEndMsg{Err: fmt.Errorf("Error writing command to temporary file: %s", err.Error())}
+		}
+		if err := tmpCmdScript.Close(); err != nil {
+			return FetchEndMsg{Err: fmt.Errorf("Error closing temporary command file: %s", err.Error())}
+		}
+
+		log.Debug(fmt.Sprintf("Executing: sh %s", tmpCmdScriptName))
+		log.Debug(fmt.Sprintf("Command: %s", cmdString))
+
+		cmd := exec.Command("sh", tmpCmdScriptName)
+		out, err := cmd.StdoutPipe()
+		if err != nil {
+			return FetchEndMsg{Err: fmt.Errorf("Error creating StdoutPipe: %s", err.Error())}
+		}
+		defer out.Close()
+
+		stderr, err := cmd.StderrPipe()
+		if err != nil {
+			return FetchEndMsg{Err: fmt.Errorf("Error creating StderrPipe: %s", err.Error())}
+		}
+		defer stderr.Close()
+
+		if err := cmd.Start(); err != nil {
+			return FetchEndMsg{Err: fmt.Errorf("Error starting program: %s", err.Error())}
+		}
+
+		parser := vsl.NewParser(out)
+		parser.OnError = func(err error) {
+			log.Debug(fmt.Sprintf("Error parsing tx: %s", err.Error()))
+		}
+		errReader := bufio.NewReader(stderr)
+
+		// Channel to collect stderr output
+		errChan := make(chan string)
+		go func() {
+			var stderrContent strings.Builder
+			for {
+				line, err := errReader.ReadString('\n')
+				if line = strings.TrimRight(line, "\n"); line != "" {
+					stderrContent.WriteString(line + "\n")
+					log.Debug(fmt.Sprintf("stderr: %s", line))
+				}
+				if err != nil {
+					if err != io.EOF {
+						log.Debug(fmt.Sprintf("Error reading from stderr: %s", err))
+					}
+					break
+				}
+			}
+			errChan <- stderrContent.String()
+			close(errChan)
+		}()
+
+		for parser.Next() {
+			stats.store(parser.Stats())
+			select {
+			case <-cancelChan:
+				err := cmd.Process.Kill()
+				if err != nil {
+					return FetchEndMsg{Err: fmt.Errorf("Could not kill the process: %s", err.Error())}
+				}
+				return FetchEndMsg{}
+			case txChan <- New(*parser.Tx()):
+			}
+		}
+		stats.store(parser.Stats())
+
+		var endMsg = FetchEndMsg{}
+		if err := parser.Err(); err != nil {
+			endMsg.Err = fmt.Errorf("Error reading from stdout: %s", err.Error())
+		}
+
+		cmd.WaitDelay = time.Second
+		if err := cmd.Wait(); err != nil {
+			stderrOutput := <-errChan
+			endMsg.Err = fmt.Errorf("Error: %s %s", err.Error(), stderrOutput)
+		}
+
+		return endMsg
+	}
+}
+
+// ListenForTxsCmd waits for the next tx and collects the ones that arrive
+// during txBatchWindow, it returns nil once txChan is closed and drained
+func ListenForTxsCmd(txChan chan Tx) tea.Cmd {
+	return func() tea.Msg {
+		first, ok := <-txChan
+		if !ok {
+			return nil
+		}
+
+		batch := []Tx{first}
+		timeout := time.After(txBatchWindow)
+		for len(batch) < MaxTxBatch {
+			select {
+			case t, ok := <-txChan:
+				if !ok {
+					return NewTxsMsg{Txs: batch, Source: txChan}
+				}
+				batch = append(batch, t)
+			case <-timeout:
+				return NewTxsMsg{Txs: batch, Source: txChan}
+			}
+		}
+		return NewTxsMsg{Txs: batch, Source: txChan}
+	}
+}
@@ -0,0 +1,46 @@
+package tx
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/aorith/varnishlog-tui/pkg/vsl"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/log"
+)
+
+// ReadVSLFileAndFetchTxs reads the txs of a binary VSL file written by varnishlog -w
+func ReadVSLFileAndFetchTxs(path string, cancelChan chan struct{}, txChan chan Tx, stats *FetchStats) tea.Cmd {
+	return func() tea.Msg {
+		defer close(txChan)
+
+		f, err := os.Open(path)
+		if err != nil {
+			return FetchEndMsg{Err: fmt.Errorf("Error opening VSL file: %s", err.Error())}
+		}
+		defer f.Close()
+
+		parser, err := vsl.NewBinaryParser(f)
+		if err != nil {
+			return FetchEndMsg{Err: fmt.Errorf("Error reading VSL file %s: %s", path, err.Error())}
+		}
+		parser.OnError = func(err error) {
+			log.Debug(fmt.Sprintf("Error parsing tx: %s", err.Error()))
+		}
+
+		for parser.Next() {
+			stats.store(parser.Stats())
+			select {
+			case <-cancelChan:
+				return FetchEndMsg{}
+			case txChan <- New(*parser.Tx()):
+			}
+		}
+		stats.store(parser.Stats())
+
+		if err := parser.Err(); err != nil {
+			return FetchEndMsg{Err: fmt.Errorf("Error reading VSL file %s: %s", path, err.Error())}
+		}
+		return FetchEndMsg{}
+	}
+}
@@ -12,6 +12,7 @@ import (
 type report struct {
 	TxsTotalTimeHistogram string
 	TxsStateDiagram       string
+	Waterfall             waterfallReport
 	AccountingReceived    string
 	AccountingTransmitted string
 	Txs                   []reportTx
@@ -24,7 +25,42 @@ type reportTx struct {
 	TimestampHistogram string
 	TransitionsDiagram string
 	TxInfoTable        []verticalTableRow
+	BackendTable       []verticalTableRow
 	TTLTable           horizontalTable
+	HeadersTables      []titledTable
+	HeaderChangesTable horizontalTable
+	EventsTable        horizontalTable
+}
+
+type waterfallReport struct {
+	Span   string
+	Phases []string
+	Bars   []waterfallReportBar
+}
+
+// waterfallReportBar positions the bar and its segments as percentages of the span
+type waterfallReportBar struct {
+	Txid     string
+	Label    string
+	Offset   string
+	Duration string
+	Left     float64
+	Width    float64
+	Segments []waterfallReportSegment
+}
+
+type waterfallReportSegment struct {
+	Phase string
+	Title string
+	Left  float64 // Relative to the bar
+	Width float64
+}
+
+// flowReport is the aggregated VCL flow of many txs
+type flowReport struct {
+	Txs     int
+	Diagram string
+	Table   horizontalTable
 }
 
 type horizontalTable struct {
@@ -32,6 +68,11 @@ type horizontalTable struct {
 	Rows    [][]string
 }
 
+type titledTable struct {
+	Title string
+	Table horizontalTable
+}
+
 type verticalTableRow struct {
 	Header string
 	Values []string
@@ -97,11 +138,48 @@ func collectDiagramRelationships(tx *Tx, relationships map[string]struct{}, subg
 	}
 }
 
+// newWaterfallReport converts the waterfall of the group into the bars of the HTML report
+func (t *Tx) newWaterfallReport() waterfallReport {
+	w := t.Waterfall()
+	rep := waterfallReport{Span: w.Span.String(), Phases: WaterfallPhases}
+
+	for _, bar := range w.Bars {
+		repBar := waterfallReportBar{
+			Txid:     bar.Tx.Txid,
+			Label:    fmt.Sprintf("%s %s %s", bar.Tx.Txid, bar.Tx.RecordType, bar.Tx.Reason),
+			Offset:   bar.Start.String(),
+			Duration: (bar.End - bar.Start).String(),
+			Left:     w.Percent(bar.Start),
+			Width:    w.Percent(bar.End - bar.Start),
+		}
+		for _, seg := range bar.Segments {
+			if seg.End <= seg.Start || bar.End <= bar.Start {
+				continue
+			}
+			barSpan := float64(bar.End - bar.Start)
+			repBar.Segments = append(repBar.Segments, waterfallReportSegment{
+				Phase: seg.Phase,
+				Title: fmt.Sprintf("%s %s", seg.Label, seg.End-seg.Start),
+				Left:  float64(seg.Start-bar.Start) * 100 / barSpan,
+				Width: float64(seg.End-seg.Start) * 100 / barSpan,
+			})
+		}
+		rep.Bars = append(rep.Bars, repBar)
+	}
+
+	return rep
+}
+
 // newTxInfoTable generates an HTML table with basic info about the tx.
-// if the tx is a session nothing is returned
+// if the tx is a session only the client info is returned
 func (t Tx) newTxInfoTable() []verticalTableRow {
+	var incompleteRows []verticalTableRow
+	if t.Incomplete {
+		incompleteRows = append(incompleteRows, verticalTableRow{Header: "Incomplete", Values: []string{"End was not logged, some records may be missing"}})
+	}
+
 	if t.RecordType == "sess" {
-		return nil
+		return append(incompleteRows, t.newTxClientRows()...)
 	}
 
 	// values
@@ -121,14 +199,73 @@ func (t Tx) newTxInfoTable() []verticalTableRow {
 		childrenStr = strings.Join(children, ", ")
 	}
 
-	var rows []verticalTableRow
+	rows := incompleteRows
 	rows = append(rows,
 		verticalTableRow{Header: "Parent", Values: []string{parentTxid}},
 		verticalTableRow{Header: "Reason", Values: []string{t.Reason}},
 		verticalTableRow{Header: "Request", Values: []string{fmt.Sprintf("%s %s%s", t.Method, t.Host, t.Url)}},
 		verticalTableRow{Header: "Status", Values: []string{fmt.Sprintf("%d %s", t.StatusCode, t.StatusReason)}},
+		verticalTableRow{Header: "Cache", Values: []string{t.cacheOutcomeDescription()}},
 		verticalTableRow{Header: "Children", Values: []string{childrenStr}},
 	)
+	rows = append(rows, t.newTxClientRows()...)
+
+	return rows
+}
+
+// newTxClientRows generates the rows with the client info of the tx
+func (t Tx) newTxClientRows() []verticalTableRow {
+	c := t.Client
+	if c.IP == "" {
+		return nil
+	}
+
+	rows := []verticalTableRow{
+		{Header: "Client", Values: []string{c.Addr()}},
+	}
+	if c.Listener != "" {
+		rows = append(rows, verticalTableRow{Header: "Listener", Values: []string{c.Listener}})
+	}
+	if c.LocalAddr != "" {
+		rows = append(rows, verticalTableRow{Header: "Local", Values: []string{c.LocalAddr}})
+	}
+	if c.Protocol != "" {
+		rows = append(rows, verticalTableRow{Header: "Protocol", Values: []string{c.Protocol}})
+	}
+	if len(c.XForwardedFor) > 0 {
+		rows = append(rows, verticalTableRow{Header: "X-Forwarded-For", Values: []string{strings.Join(c.XForwardedFor, ", ")}})
+	}
+	if !c.OpenTime.IsZero() {
+		rows = append(rows, verticalTableRow{Header: "Session opened", Values: []string{c.OpenTime.String()}})
+	}
+
+	return rows
+}
+
+// newTxBackendTable generates an HTML table with the backend connection of a bereq
+func (t Tx) newTxBackendTable() []verticalTableRow {
+	if t.RecordType != "bereq" {
+		return nil
+	}
+
+	b := t.Backend
+	orDash := func(s string) string {
+		if s == "" {
+			return "-"
+		}
+		return s
+	}
+
+	rows := []verticalTableRow{
+		{Header: "Backend", Values: []string{orDash(b.Name)}},
+		{Header: "Remote", Values: []string{orDash(b.RemoteAddr)}},
+		{Header: "Local", Values: []string{orDash(b.LocalAddr)}},
+		{Header: "Connection", Values: []string{b.ConnectionDescription()}},
+		{Header: "Body", Values: []string{orDash(b.FetchBody)}},
+	}
+	for _, fetchErr := range b.FetchErrors {
+		rows = append(rows, verticalTableRow{Header: "FetchError", Values: []string{fetchErr}})
+	}
 
 	return rows
 }
@@ -175,6 +312,38 @@ func (t Tx) newTxTTLTable() (headers []string, rows [][]string) {
 	return headers, rows
 }
 
+// newTxHeadersTables generates a table for each header family of the tx,
+// families modified by VCL get a table for the original and the final headers
+func (t Tx) newTxHeadersTables() []titledTable {
+	var tables []titledTable
+	headers := []string{"Name", "Value"}
+
+	for _, hs := range t.HeaderFamilies() {
+		if len(hs.Set.Original) == 0 && len(hs.Set.Final) == 0 {
+			continue
+		}
+		if hs.Set.Modified() {
+			tables = append(tables,
+				titledTable{
+					Title: hs.Name + " (original)",
+					Table: horizontalTable{Headers: headers, Rows: headersToRows(hs.Set.Original)},
+				},
+				titledTable{
+					Title: hs.Name + " (final)",
+					Table: horizontalTable{Headers: headers, Rows: headersToRows(hs.Set.Final)},
+				},
+			)
+		} else {
+			tables = append(tables, titledTable{
+				Title: hs.Name,
+				Table: horizontalTable{Headers: headers, Rows: headersToRows(hs.Set.Final)},
+			})
+		}
+	}
+
+	return tables
+}
+
 // generateTransitionsDiagram generates a mermaid diagram with the VCL transition states
 func (t Tx) generateTransitionsDiagram() string {
 	if t.RecordType == "sess" || len(t.Transitions) <= 0 {
@@ -213,7 +382,21 @@ func (t Tx) GenerateHtmlReport() ([]string, error) {
 			TimestampHistogram: tx.GenerateTimestampHistogram(),
 			RawTx:              strings.Join(tx.RawTx, "\n"),
 			TxInfoTable:        tx.newTxInfoTable(),
+			BackendTable:       tx.newTxBackendTable(),
 			TransitionsDiagram: tx.generateTransitionsDiagram(),
+			HeadersTables:      tx.newTxHeadersTables(),
+		}
+
+		eventsHeaders, eventsRows := tx.eventsRows()
+		repTx.EventsTable = horizontalTable{
+			Headers: eventsHeaders,
+			Rows:    eventsRows,
+		}
+
+		changesHeaders, changesRows := tx.headerChangesRows()
+		repTx.HeaderChangesTable = horizontalTable{
+			Headers: changesHeaders,
+			Rows:    changesRows,
 		}
 
 		if tx.RecordType != "sess" {
@@ -230,6 +413,7 @@ func (t Tx) GenerateHtmlReport() ([]string, error) {
 	report := report{
 		TxsTotalTimeHistogram: t.GenerateAllTxsHistogram(txs),
 		TxsStateDiagram:       t.generateAllTxsDiagram(parent),
+		Waterfall:             t.newWaterfallReport(),
 		AccountingReceived:    t.GenerateAccountingHistogram(txs, false),
 		AccountingTransmitted: t.GenerateAccountingHistogram(txs, true),
 		Txs:                   repTxs,
@@ -250,3 +434,37 @@ func (t Tx) GenerateHtmlReport() ([]string, error) {
 
 	return html, nil
 }
+
+// GenerateFlowHtmlReport generates a report with the VCL transitions of all the txs and their share
+func GenerateFlowHtmlReport(txs []*Tx) ([]string, error) {
+	flow := NewFlow(txs)
+	headers, rows := flow.flowRows()
+	report := flowReport{
+		Txs:     flow.Txs,
+		Diagram: flow.generateFlowDiagram(),
+		Table:   horizontalTable{Headers: headers, Rows: rows},
+	}
+
+	tmpl, err := template.New("flow").Parse(assets.FlowTemplate)
+	if err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	err = tmpl.Execute(&buf, report)
+	if err != nil {
+		return nil, err
+	}
+
+	return strings.Split(buf.String(), "\n"), nil
+}
+
+// cacheOutcomeDescription returns the outcome along with the origin of the object
+//
+//	hit (object from 32771)
+func (t Tx) cacheOutcomeDescription() string {
+	if t.HitVxid != 0 {
+		return fmt.Sprintf("%s (object from %d)", t.CacheOutcome, t.HitVxid)
+	}
+	return t.CacheOutcome.String()
+}
@@ -6,6 +6,7 @@ import (
 	"time"
 
 	"github.com/aorith/varnishlog-tui/internal/util"
+	"github.com/aorith/varnishlog-tui/pkg/vsl"
 )
 
 // printTimestampsFlow returns a string represening the sequence of the timestamps and their durations
@@ -112,13 +113,13 @@ func (t Tx) GenerateAccountingHistogram(txs []*Tx, transmitted bool) string {
 			rowSum = 0
 		} else {
 			if transmitted {
-				row = append(row, tx.Accounting.HeaderBytesTransmitted.String())
-				row = append(row, tx.Accounting.BodyBytesTransmitted.String())
-				rowSum = tx.Accounting.HeaderBytesTransmitted.Value() + tx.Accounting.BodyBytesTransmitted.Value()
+				row = append(row, util.SizeValue(tx.Accounting.HeaderBytesTransmitted).String())
+				row = append(row, util.SizeValue(tx.Accounting.BodyBytesTransmitted).String())
+				rowSum = tx.Accounting.HeaderBytesTransmitted + tx.Accounting.BodyBytesTransmitted
 			} else {
-				row = append(row, tx.Accounting.HeaderBytesReceived.String())
-				row = append(row, tx.Accounting.BodyBytesReceived.String())
-				rowSum = tx.Accounting.HeaderBytesReceived.Value() + tx.Accounting.BodyBytesReceived.Value()
+				row = append(row, util.SizeValue(tx.Accounting.HeaderBytesReceived).String())
+				row = append(row, util.SizeValue(tx.Accounting.BodyBytesReceived).String())
+				rowSum = tx.Accounting.HeaderBytesReceived + tx.Accounting.BodyBytesReceived
 			}
 			total += rowSum
 		}
@@ -164,3 +165,137 @@ func (t Tx) GenerateAllTxsHistogram(txs []*Tx) string {
 
 	return util.GenerateHistogram(headers, rowValues)
 }
+
+// GenerateHeadersTables generates an ASCII table for each header family of this tx,
+// families modified by VCL show the original and the final headers
+func (t Tx) GenerateHeadersTables() string {
+	var s strings.Builder
+
+	for _, hs := range t.HeaderFamilies() {
+		if len(hs.Set.Original) == 0 && len(hs.Set.Final) == 0 {
+			continue
+		}
+		if hs.Set.Modified() {
+			s.WriteString(fmt.Sprintf("\n%s headers (original)\n", hs.Name))
+			s.WriteString(util.GenerateTable([]string{"Name", "Value"}, headersToRows(hs.Set.Original)))
+			s.WriteString(fmt.Sprintf("\n%s headers (final)\n", hs.Name))
+		} else {
+			s.WriteString(fmt.Sprintf("\n%s headers\n", hs.Name))
+		}
+		s.WriteString(util.GenerateTable([]string{"Name", "Value"}, headersToRows(hs.Set.Final)))
+	}
+
+	return s.String()
+}
+
+// headersToRows converts the headers into table rows
+func headersToRows(headers vsl.Headers) [][]string {
+	rows := make([][]string, len(headers))
+	for i, hdr := range headers {
+		rows[i] = []string{hdr.Name, hdr.Value}
+	}
+	return rows
+}
+
+// GenerateHeaderChangesTable generates an ASCII table with the URL and header changes
+// made by each VCL subroutine
+func (t Tx) GenerateHeaderChangesTable() string {
+	headers, rows := t.headerChangesRows()
+	if len(rows) == 0 {
+		return ""
+	}
+	return util.GenerateTable(headers, rows)
+}
+
+// GenerateEventsTable generates an ASCII table with the events of this tx
+func (t Tx) GenerateEventsTable() string {
+	headers, rows := t.eventsRows()
+	if len(rows) == 0 {
+		return ""
+	}
+	return util.GenerateTable(headers, rows)
+}
+
+// headerChangesRows converts the header timeline into table rows, the subroutine
+// is only shown in the first row of each group
+func (t Tx) headerChangesRows() (headers []string, rows [][]string) {
+	timeline := t.HeaderTimeline()
+	if len(timeline) == 0 {
+		return nil, nil
+	}
+
+	headers = []string{"Subroutine", "Change", "Name", "Value"}
+	for _, sc := range timeline {
+		for i, c := range sc.Changes {
+			sub := ""
+			if i == 0 {
+				sub = vsl.SubName(sc.Sub)
+			}
+			value := c.Value
+			if c.OldValue != "" {
+				value = fmt.Sprintf("%s (was %s)", c.Value, c.OldValue)
+			}
+			rows = append(rows, []string{sub, c.Action + " " + strings.ToLower(c.Family), c.Name, value})
+		}
+	}
+	return headers, rows
+}
+
+// eventsRows converts the events into table rows
+func (t Tx) eventsRows() (headers []string, rows [][]string) {
+	if len(t.Events) == 0 {
+		return nil, nil
+	}
+
+	headers = []string{"Tag", "Subroutine", "Message"}
+	for _, e := range t.Events {
+		sub := "-"
+		if e.Sub != "" {
+			sub = vsl.SubName(e.Sub)
+		}
+		rows = append(rows, []string{e.Tag, sub, e.Message})
+	}
+	return headers, rows
+}
+
+// GenerateSummary generates the info, client and backend details of this tx as aligned lines
+//
+//	Parent  : 32769
+//	Reason  : rxreq
+//	Request : GET www.example.com/path/to/asset
+func (t Tx) GenerateSummary() string {
+	rows := append(t.newTxInfoTable(), t.newTxBackendTable()...)
+
+	var width int
+	for _, row := range rows {
+		width = max(width, len(row.Header))
+	}
+
+	var s strings.Builder
+	for _, row := range rows {
+		s.WriteString(fmt.Sprintf("%-*s : %s\n", width, row.Header, strings.Join(row.Values, ", ")))
+	}
+	return s.String()
+}
+
+// GenerateTransitionsTable generates an ASCII table with the VCL subroutines called and their returns
+func (t Tx) GenerateTransitionsTable() string {
+	if len(t.Transitions) == 0 {
+		return ""
+	}
+
+	rows := make([][]string, len(t.Transitions))
+	for i, tr := range t.Transitions {
+		rows[i] = []string{fmt.Sprintf("%d", i+1), vsl.SubName(tr.Call), tr.Return}
+	}
+	return util.GenerateTable([]string{"#", "Subroutine", "Return"}, rows)
+}
+
+// GenerateTTLTable generates an ASCII table with the TTL records of this tx
+func (t Tx) GenerateTTLTable() string {
+	headers, rows := t.newTxTTLTable()
+	if len(rows) == 0 {
+		return ""
+	}
+	return util.GenerateTable(headers, rows)
+}
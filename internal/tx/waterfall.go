@@ -0,0 +1,213 @@
+package tx
+
+import (
+	"cmp"
+	"fmt"
+	"math"
+	"slices"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// waterfallLength is the number of characters of the bars of the ASCII waterfall
+const waterfallLength = 60
+
+// Phases of the waterfall, the timestamps of req and bereq txs are grouped into them
+const (
+	PhaseStart   = "Start"
+	PhaseReq     = "Req"
+	PhaseFetch   = "Fetch"
+	PhaseProcess = "Process"
+	PhaseResp    = "Resp"
+)
+
+// WaterfallPhases are the phases in the order they usually happen
+var WaterfallPhases = []string{PhaseStart, PhaseReq, PhaseFetch, PhaseProcess, PhaseResp}
+
+// waterfallGlyphs are the characters that fill each phase in the ASCII waterfall
+var waterfallGlyphs = map[string]rune{
+	PhaseStart:   '│',
+	PhaseReq:     '░',
+	PhaseFetch:   '▓',
+	PhaseProcess: '▒',
+	PhaseResp:    '█',
+}
+
+// WaterfallSegment is the time spent in a phase, the offsets are relative to the start of the group
+type WaterfallSegment struct {
+	Phase string
+	Label string // Label of the timestamp that ends the segment
+	Start time.Duration
+	End   time.Duration
+}
+
+// WaterfallBar is the time span of a tx inside its group
+type WaterfallBar struct {
+	Tx       *Tx
+	Start    time.Duration
+	End      time.Duration
+	Segments []WaterfallSegment
+}
+
+// Waterfall shows how the txs of a group overlap in time
+type Waterfall struct {
+	Start time.Time     // Earliest timestamp of the group
+	Span  time.Duration // From the earliest to the latest timestamp of the group
+	Bars  []WaterfallBar
+}
+
+// timestampPhase returns the phase that a timestamp label closes
+func timestampPhase(label string) string {
+	switch label {
+	case "Start":
+		return PhaseStart
+	case "Req", "ReqBody", "Waitinglist", "Restart", "Bereq":
+		return PhaseReq
+	case "Fetch", "Beresp", "BerespBody", "Retry", "Pipe":
+		return PhaseFetch
+	case "Resp", "PipeSess":
+		return PhaseResp
+	default:
+		return PhaseProcess
+	}
+}
+
+// Waterfall builds the waterfall of the group of the tx from the absolute timestamps,
+// txs without timestamps like sessions are left out
+func (t *Tx) Waterfall() Waterfall {
+	var w Waterfall
+
+	for _, g := range t.Group() {
+		if len(g.Timestamps) == 0 {
+			continue
+		}
+		if first := g.Timestamps[0].Absolute; w.Start.IsZero() || first.Before(w.Start) {
+			w.Start = first
+		}
+	}
+
+	for _, g := range t.Group() {
+		if len(g.Timestamps) == 0 {
+			continue
+		}
+		bar := WaterfallBar{
+			Tx:    g,
+			Start: g.Timestamps[0].Absolute.Sub(w.Start),
+			End:   g.Timestamps[len(g.Timestamps)-1].Absolute.Sub(w.Start),
+		}
+		for _, ts := range g.Timestamps {
+			end := ts.Absolute.Sub(w.Start)
+			bar.Segments = append(bar.Segments, WaterfallSegment{
+				Phase: timestampPhase(ts.EventLabel),
+				Label: ts.EventLabel,
+				Start: end - ts.SinceLast,
+				End:   end,
+			})
+		}
+		w.Bars = append(w.Bars, bar)
+		w.Span = max(w.Span, bar.End)
+	}
+
+	return w
+}
+
+// Percent returns the offset as a percentage of the span of the waterfall
+func (w Waterfall) Percent(d time.Duration) float64 {
+	if w.Span <= 0 {
+		return 0
+	}
+	return float64(d) * 100 / float64(w.Span)
+}
+
+// cells returns the characters of a bar scaled to length, segments of any duration take at least one character
+func (w Waterfall) cells(bar WaterfallBar, length int) []rune {
+	cells := []rune(strings.Repeat(" ", length))
+	scale := func(d time.Duration) float64 {
+		return w.Percent(d) * float64(length) / 100
+	}
+
+	// The bar is marked even when all its timestamps are at the same instant
+	cells[min(int(scale(bar.Start)), length-1)] = waterfallGlyphs[PhaseStart]
+
+	// Longer segments are drawn first so the shorter ones sharing a character stay visible
+	segments := slices.Clone(bar.Segments)
+	slices.SortStableFunc(segments, func(a, b WaterfallSegment) int {
+		return cmp.Compare(b.End-b.Start, a.End-a.Start)
+	})
+	for _, seg := range segments {
+		if seg.End <= seg.Start {
+			continue
+		}
+		from := min(int(scale(seg.Start)), length-1)
+		to := max(min(int(math.Ceil(scale(seg.End))), length), from+1)
+		for i := from; i < to; i++ {
+			cells[i] = waterfallGlyphs[seg.Phase]
+		}
+	}
+	return cells
+}
+
+// GenerateWaterfall generates an ASCII Gantt chart of the txs of the group,
+// each bar is split into its phases and placed by the absolute timestamps
+//
+//	TxId  | Type  | Reason | Offset | Duration | 0s                                1.2ms
+//	---------------------------------------------------------------------------------
+//	32770 | req   | rxreq  | 0s     | 1.2ms    | ░▓▓▓▓▓▓▓▓▓▓▓▓▓▓▓▓▓▓▓▓▓▓▓▓▓▓▓▓▓▓▓▓▓▓▒█
+//	32771 | bereq | fetch  | 50µs   | 1.1ms    |  ░▓▓▓▓▓▓▓▓▓▓▓▓▓▓▓▓▓▓▓▓▓▓▓▓▓▓▓▓▓▓▓▓▓
+//
+//	│ Start  ░ Req  ▓ Fetch  ▒ Process  █ Resp
+func (t *Tx) GenerateWaterfall() string {
+	w := t.Waterfall()
+	if len(w.Bars) == 0 {
+		return ""
+	}
+
+	headers := []string{"TxId", "Type", "Reason", "Offset", "Duration"}
+	rows := make([][]string, len(w.Bars))
+	maxLens := make([]int, len(headers))
+	for i, h := range headers {
+		maxLens[i] = len(h)
+	}
+	for i, bar := range w.Bars {
+		txid := bar.Tx.Txid
+		if bar.Tx.Txid == t.Txid {
+			txid += "*"
+		}
+		rows[i] = []string{txid, bar.Tx.RecordType, bar.Tx.Reason, bar.Start.String(), (bar.End - bar.Start).String()}
+		for j, col := range rows[i] {
+			maxLens[j] = max(maxLens[j], utf8.RuneCountInString(col))
+		}
+	}
+
+	var s strings.Builder
+	writeCols := func(cols []string) {
+		for j, col := range cols {
+			s.WriteString(fmt.Sprintf("%-*s | ", maxLens[j], col))
+		}
+	}
+
+	s.WriteRune('\n')
+	writeCols(headers)
+	spanStr := w.Span.String()
+	s.WriteString(fmt.Sprintf("0s%*s\n", waterfallLength-2, spanStr))
+	width := len(headers)*3 + waterfallLength
+	for _, l := range maxLens {
+		width += l
+	}
+	s.WriteString(strings.Repeat("-", width) + "\n")
+
+	for i, bar := range w.Bars {
+		writeCols(rows[i])
+		s.WriteString(strings.TrimRight(string(w.cells(bar, waterfallLength)), " "))
+		s.WriteRune('\n')
+	}
+
+	legend := make([]string, len(WaterfallPhases))
+	for i, phase := range WaterfallPhases {
+		legend[i] = fmt.Sprintf("%c %s", waterfallGlyphs[phase], phase)
+	}
+	s.WriteString("\n" + strings.Join(legend, "  ") + "\n")
+
+	return s.String()
+}
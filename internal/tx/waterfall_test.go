@@ -0,0 +1,98 @@
+package tx
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aorith/varnishlog-tui/pkg/vsl"
+)
+
+// newTestTimestamps returns the timestamps of the labels at the given offsets from start
+func newTestTimestamps(start time.Time, labels []string, offsets []time.Duration) []vsl.Timestamp {
+	ts := make([]vsl.Timestamp, len(labels))
+	var last time.Duration
+	for i, label := range labels {
+		ts[i] = vsl.Timestamp{
+			EventLabel: label,
+			Absolute:   start.Add(offsets[i]),
+			SinceStart: offsets[i] - offsets[0],
+			SinceLast:  offsets[i] - last,
+		}
+		if i == 0 {
+			ts[i].SinceLast = 0
+		}
+		last = offsets[i]
+	}
+	return ts
+}
+
+// TestWaterfall tests that the bars of a group are placed by their absolute timestamps
+func TestWaterfall(t *testing.T) {
+	start := time.Date(2024, 6, 1, 10, 0, 0, 0, time.UTC)
+	s := NewStore(Retention{})
+
+	sess := newTestTx("1", 1, "2")
+	s.Add(sess)
+
+	req := newTestTx("2", 2, "3", "4")
+	req.Timestamps = newTestTimestamps(start,
+		[]string{"Start", "Req", "Fetch", "Process", "Resp"},
+		[]time.Duration{0, 100 * time.Microsecond, 800 * time.Microsecond, 900 * time.Microsecond, time.Millisecond},
+	)
+	s.Add(req)
+
+	bereq := newTestTx("3", 3)
+	bereq.Timestamps = newTestTimestamps(start,
+		[]string{"Start", "Bereq", "Beresp", "BerespBody"},
+		[]time.Duration{200 * time.Microsecond, 300 * time.Microsecond, 600 * time.Microsecond, 700 * time.Microsecond},
+	)
+	s.Add(bereq)
+
+	w := s.Get("3").Waterfall()
+
+	if !w.Start.Equal(start) {
+		t.Errorf("start: expected %v, got %v", start, w.Start)
+	}
+	if w.Span != time.Millisecond {
+		t.Errorf("span: expected %v, got %v", time.Millisecond, w.Span)
+	}
+	// The session has no timestamps and the bereq 4 was never received
+	if len(w.Bars) != 2 {
+		t.Fatalf("bars: expected 2, got %d", len(w.Bars))
+	}
+
+	bar := w.Bars[1]
+	if bar.Tx.Txid != "3" || bar.Start != 200*time.Microsecond || bar.End != 700*time.Microsecond {
+		t.Errorf("bereq bar: expected 3 from 200µs to 700µs, got %s from %v to %v", bar.Tx.Txid, bar.Start, bar.End)
+	}
+
+	tests := []struct {
+		phase      string
+		start, end time.Duration
+	}{
+		{PhaseStart, 200 * time.Microsecond, 200 * time.Microsecond},
+		{PhaseReq, 200 * time.Microsecond, 300 * time.Microsecond},
+		{PhaseFetch, 300 * time.Microsecond, 600 * time.Microsecond},
+		{PhaseFetch, 600 * time.Microsecond, 700 * time.Microsecond},
+	}
+	for i, tt := range tests {
+		seg := bar.Segments[i]
+		if seg.Phase != tt.phase || seg.Start != tt.start || seg.End != tt.end {
+			t.Errorf("segment %d: expected %s from %v to %v, got %s from %v to %v", i, tt.phase, tt.start, tt.end, seg.Phase, seg.Start, seg.End)
+		}
+	}
+
+	cells := string(w.cells(w.Bars[0], 10))
+	if cells != "░▓▓▓▓▓▓▓▒█" {
+		t.Errorf("req cells: expected %q, got %q", "░▓▓▓▓▓▓▓▒█", cells)
+	}
+	cells = string(w.cells(bar, 10))
+	if cells != "  ░▓▓▓▓   " {
+		t.Errorf("bereq cells: expected %q, got %q", "  ░▓▓▓▓   ", cells)
+	}
+
+	if out := s.Get("2").GenerateWaterfall(); !strings.Contains(out, "2*") {
+		t.Errorf("expected the selected tx to be marked in the waterfall, got:\n%s", out)
+	}
+}
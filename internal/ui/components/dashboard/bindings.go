@@ -0,0 +1,91 @@
+package dashboard
+
+import (
+	"github.com/charmbracelet/bubbles/key"
+)
+
+// keyMap defines a set of keybindings. To work for help it must satisfy
+// key.Map. It could also very easily be a map[string]key.Binding.
+type keyMap struct {
+	NextTab   key.Binding
+	PrevTab   key.Binding
+	GroupBy   key.Binding
+	Metric    key.Binding
+	Up        key.Binding
+	Down      key.Binding
+	Field     key.Binding
+	EditField key.Binding
+	Window    key.Binding
+	Select    key.Binding
+	Reset     key.Binding
+	Close     key.Binding
+}
+
+// ShortHelp returns keybindings to be shown in the mini help view. It's part
+// of the key.Map interface.
+func (k keyMap) ShortHelp() []key.Binding {
+	return []key.Binding{k.NextTab, k.GroupBy, k.Metric, k.Reset, k.Close}
+}
+
+// FullHelp returns keybindings for the expanded help view. It's part of the
+// key.Map interface.
+func (k keyMap) FullHelp() [][]key.Binding {
+	return [][]key.Binding{
+		{k.NextTab, k.PrevTab},
+		{k.GroupBy, k.Metric},
+		{k.Up, k.Down, k.Select},
+		{k.Field, k.EditField, k.Window},
+		{k.Reset, k.Close},
+	}
+}
+
+var keys = keyMap{
+	NextTab: key.NewBinding(
+		key.WithKeys("tab", "l", "right"),
+		key.WithHelp("tab/l", "next tab"),
+	),
+	PrevTab: key.NewBinding(
+		key.WithKeys("shift+tab", "h", "left"),
+		key.WithHelp("shift+tab/h", "previous tab"),
+	),
+	GroupBy: key.NewBinding(
+		key.WithKeys("g"),
+		key.WithHelp("g", "latency group"),
+	),
+	Metric: key.NewBinding(
+		key.WithKeys("m"),
+		key.WithHelp("m", "latency metric"),
+	),
+	Up: key.NewBinding(
+		key.WithKeys("k", "up"),
+		key.WithHelp("↑/k", "top: move up"),
+	),
+	Down: key.NewBinding(
+		key.WithKeys("j", "down"),
+		key.WithHelp("↓/j", "top: move down"),
+	),
+	Field: key.NewBinding(
+		key.WithKeys("c"),
+		key.WithHelp("c", "top: next field"),
+	),
+	EditField: key.NewBinding(
+		key.WithKeys("e"),
+		key.WithHelp("e", "top: type a field"),
+	),
+	Window: key.NewBinding(
+		key.WithKeys("w"),
+		key.WithHelp("w", "top: decay window"),
+	),
+	Select: key.NewBinding(
+		key.WithKeys("enter"),
+		key.WithHelp("enter", "top: filter the list"),
+	),
+	Reset: key.NewBinding(
+		key.WithKeys("x"),
+		key.WithHelp("x", "reset the statistics"),
+	),
+	Close: key.NewBinding(
+		key.WithKeys("D", "esc", "q"),
+		key.WithHelp("D/esc/q", "back to the list"),
+	),
+}
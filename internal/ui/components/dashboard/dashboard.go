@@ -0,0 +1,411 @@
+package dashboard
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/aorith/varnishlog-tui/internal/tx"
+	"github.com/aorith/varnishlog-tui/internal/ui/state"
+	"github.com/aorith/varnishlog-tui/internal/ui/styles"
+	"github.com/aorith/varnishlog-tui/internal/util"
+	"github.com/aorith/varnishlog-tui/pkg/vsl"
+	"github.com/charmbracelet/bubbles/help"
+	"github.com/charmbracelet/bubbles/key"
+	"github.com/charmbracelet/bubbles/viewport"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+const (
+	// topLength is the number of hosts and URLs shown
+	topLength = 10
+
+	// barLength is the maximum length of the status class bars
+	barLength = 30
+)
+
+var frameHoriz, frameVert = styles.MainMarginStyle.GetFrameSize()
+
+var (
+	labelStyle = styles.LabelStyle.Width(10)
+	valueStyle = styles.TxidStyle
+
+	statusClassStyles = [6]lipgloss.Style{
+		styles.PagerStyle,
+		styles.PagerStyle,
+		lipgloss.NewStyle().Foreground(styles.GreenFGColor),
+		lipgloss.NewStyle().Foreground(styles.BlueFGColor),
+		lipgloss.NewStyle().Foreground(styles.YellowFGColor),
+		lipgloss.NewStyle().Foreground(styles.BrightRedFGColor),
+	}
+
+	outcomes = []vsl.CacheOutcome{
+		vsl.OutcomeHit, vsl.OutcomeMiss, vsl.OutcomePass, vsl.OutcomePipe,
+		vsl.OutcomeSynth, vsl.OutcomeHitMiss, vsl.OutcomeHitPass, vsl.OutcomeUnknown,
+	}
+)
+
+// renderTickMsg re-renders the Top tab while no txs arrive, so the live counts keep decaying
+type renderTickMsg struct{}
+
+// tab is a section of the dashboard
+type tab struct {
+	name   string
+	render func(m Model) string
+}
+
+var tabs = []tab{
+	{"Overview", Model.overviewView},
+	{"Latency", Model.latencyView},
+	{"Histogram", Model.histogramView},
+	{"Top", Model.rankingView},
+}
+
+// Model shows the aggregated statistics of all the txs received since the capture
+// was started or cleared, including the ones evicted from the list
+type Model struct {
+	stats    *tx.Stats
+	cleared  chan tx.Tx // Source of the txs discarded by the list, its late batches are ignored
+	topHosts []tx.Counter
+	topURLs  []tx.Counter
+	active   int
+	groupBy  tx.LatencyGroupBy
+	metric   tx.LatencyMetric
+	ranking  *ranking
+	visible  bool // The statistics are only rendered while the dashboard is shown
+	ticking  bool
+	viewport viewport.Model
+	help     help.Model
+	width    int
+	height   int
+}
+
+func New() Model {
+	return Model{
+		stats:    tx.NewStats(),
+		ranking:  newRanking(),
+		viewport: viewport.New(0, 0),
+		help:     help.New(),
+	}
+}
+
+func (m Model) Init() tea.Cmd {
+	return nil
+}
+
+func (m Model) Update(msg tea.Msg) (Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case tea.KeyMsg:
+		if tabs[m.active].name == "Top" {
+			if handled, cmd := m.ranking.update(msg); handled {
+				m.render()
+				return m, cmd
+			}
+		}
+		switch {
+		case key.Matches(msg, keys.Reset):
+			m.reset()
+		case key.Matches(msg, keys.Close):
+			return m, switchToLogView()
+		case key.Matches(msg, keys.NextTab):
+			m.switchTab((m.active + 1) % len(tabs))
+		case key.Matches(msg, keys.PrevTab):
+			m.switchTab((m.active + len(tabs) - 1) % len(tabs))
+		case key.Matches(msg, keys.GroupBy) && tabs[m.active].name == "Latency":
+			m.groupBy = m.groupBy.Next()
+		case key.Matches(msg, keys.Metric) && tabs[m.active].name == "Latency":
+			m.metric = m.metric.Next()
+		default:
+			if n, err := strconv.Atoi(msg.String()); err == nil && n >= 1 && n <= len(tabs) {
+				m.switchTab(n - 1)
+				break
+			}
+			var cmd tea.Cmd
+			m.viewport, cmd = m.viewport.Update(msg)
+			return m, cmd
+		}
+		m.render()
+		return m, m.tickCmd()
+	case renderTickMsg:
+		m.ticking = false
+		if !m.visible || tabs[m.active].name != "Top" {
+			break
+		}
+		m.render()
+		return m, m.tickCmd()
+	case tea.WindowSizeMsg:
+		m.width, m.height = msg.Width-frameHoriz, msg.Height-frameVert
+		m.help.Width = m.width
+		m.viewport.Width = m.width
+		m.viewport.Height = max(m.height-lipgloss.Height(m.headerView())-lipgloss.Height(m.footerView()), 1)
+		m.render()
+	case tx.NewTxsMsg:
+		if msg.Source == m.cleared {
+			break
+		}
+		for i := range msg.Txs {
+			m.stats.Add(&msg.Txs[i])
+			m.ranking.add(&msg.Txs[i])
+		}
+		m.render()
+	case tx.TxsClearedMsg:
+		m.cleared = msg.Source
+		m.reset()
+		m.render()
+	}
+	return m, nil
+}
+
+// SetVisible is called when the dashboard is shown or hidden, it is rendered when shown
+func (m *Model) SetVisible(visible bool) tea.Cmd {
+	m.visible = visible
+	if !visible {
+		return nil
+	}
+	m.render()
+	return m.tickCmd()
+}
+
+// tickCmd starts the re-rendering of the Top tab if it is shown and not ticking already
+func (m *Model) tickCmd() tea.Cmd {
+	if !m.visible || tabs[m.active].name != "Top" || m.ticking {
+		return nil
+	}
+	m.ticking = true
+	return tea.Tick(time.Second, func(time.Time) tea.Msg {
+		return renderTickMsg{}
+	})
+}
+
+func (m *Model) switchTab(i int) {
+	m.active = i
+	m.viewport.GotoTop()
+}
+
+// render sets the content of the active tab keeping the scroll, nothing is done while hidden
+func (m *Model) render() {
+	if !m.visible {
+		return
+	}
+	if tabs[m.active].name == "Overview" {
+		m.topHosts = m.stats.TopHosts(topLength)
+		m.topURLs = m.stats.TopURLs(topLength)
+	}
+	m.viewport.SetContent(tabs[m.active].render(*m))
+}
+
+func (m *Model) reset() {
+	m.stats = tx.NewStats()
+	m.topHosts, m.topURLs = nil, nil
+	_ = m.ranking.reset(m.ranking.top.Field())
+}
+
+func (m Model) View() string {
+	return lipgloss.JoinVertical(lipgloss.Left, m.headerView(), m.viewport.View(), m.footerView())
+}
+
+// headerView renders the title and the tabs
+//
+//	Dashboard · since 2024-06-01 10:00:00
+//	1 Overview │ 2 Latency │ 3 Histogram │ 4 Top
+func (m Model) headerView() string {
+	var since string
+	if !m.stats.First.IsZero() {
+		since = fmt.Sprintf(" · since %s", m.stats.First.Format("2006-01-02 15:04:05"))
+	}
+
+	names := make([]string, len(tabs))
+	for i, t := range tabs {
+		name := fmt.Sprintf("%d %s", i+1, t.name)
+		if i == m.active {
+			names[i] = styles.TitleStyle.Underline(true).Render(name)
+		} else {
+			names[i] = styles.PagerStyle.Render(name)
+		}
+	}
+
+	return lipgloss.JoinVertical(lipgloss.Left,
+		styles.TitleStyle.Render("Dashboard")+styles.PagerStyle.Render(since),
+		lipgloss.NewStyle().MaxWidth(m.width).Render(strings.Join(names, styles.PagerStyle.Render(" │ "))),
+		"",
+	)
+}
+
+func (m Model) footerView() string {
+	return lipgloss.JoinVertical(lipgloss.Left, "", m.help.View(keys))
+}
+
+// overviewView renders the traffic, the status classes and the top hosts and URLs
+func (m Model) overviewView() string {
+	s := m.stats
+
+	traffic := lipgloss.JoinVertical(lipgloss.Left,
+		styles.TitleStyle.Render("Traffic"),
+		"",
+		labelStyle.Render("Requests")+valueStyle.Render(fmt.Sprintf("%d", s.Reqs))+
+			styles.PagerStyle.Render(fmt.Sprintf(" %.1f/s now, %.1f/s avg", s.Rate(), s.AverageRate())),
+		labelStyle.Render("Fetches")+valueStyle.Render(fmt.Sprintf("%d", s.Bereqs))+
+			styles.PagerStyle.Render(fmt.Sprintf(" %d failed or 5xx", s.BackendErrors)),
+		labelStyle.Render("Sessions")+valueStyle.Render(fmt.Sprintf("%d", s.Sessions)),
+		"",
+		labelStyle.Render("Client")+fmt.Sprintf("in %s  out %s",
+			valueStyle.Render(util.SizeValue(s.ClientIn).String()), valueStyle.Render(util.SizeValue(s.ClientOut).String())),
+		labelStyle.Render("Backend")+fmt.Sprintf("in %s  out %s",
+			valueStyle.Render(util.SizeValue(s.BackendIn).String()), valueStyle.Render(util.SizeValue(s.BackendOut).String())),
+		"",
+		m.cacheView(),
+	)
+
+	sections := []string{lipgloss.JoinHorizontal(lipgloss.Top, traffic, "    ", m.statusView()), ""}
+
+	// The top lists are side by side when they fit
+	colWidth := m.width
+	if m.width >= 120 {
+		colWidth = m.width/2 - 2
+	}
+	hosts := topView("Top hosts", m.topHosts, s.Reqs, colWidth)
+	urls := topView("Top URLs", m.topURLs, s.Reqs, colWidth)
+	if colWidth < m.width {
+		sections = append(sections, lipgloss.JoinHorizontal(lipgloss.Top, hosts, "    ", urls))
+	} else {
+		sections = append(sections, hosts, "", urls)
+	}
+
+	return lipgloss.NewStyle().MaxWidth(m.width).Render(lipgloss.JoinVertical(lipgloss.Left, sections...))
+}
+
+// latencyView renders the percentiles of the metric for each group
+//
+//	Requests by host · total duration
+//
+//	Host            | Count | p50   | p90   | p99    | Max
+//	------------------------------------------------------
+//	www.example.com | 950   | 1.2ms | 8ms   | 120ms  | 1.5s
+func (m Model) latencyView() string {
+	subject := "Requests"
+	if m.groupBy == tx.GroupByBackend {
+		subject = "Backend requests"
+	}
+	title := styles.TitleStyle.Render(fmt.Sprintf("%s by %s", subject, m.groupBy)) +
+		styles.PagerStyle.Render(fmt.Sprintf(" · %s duration", m.metric))
+
+	rows := m.stats.Latencies.Rows(m.groupBy, m.metric)
+	if len(rows) == 0 {
+		return lipgloss.JoinVertical(lipgloss.Left, title, "", styles.PagerStyle.Render("No timestamps yet"))
+	}
+
+	tableRows := make([][]string, len(rows))
+	for i, r := range rows {
+		tableRows[i] = []string{
+			util.TruncateString(r.Key, 60),
+			fmt.Sprintf("%d", r.Count),
+			r.P50.String(),
+			r.P90.String(),
+			r.P99.String(),
+			r.Max.String(),
+		}
+	}
+	headers := []string{strings.ToUpper(m.groupBy.String()[:1]) + m.groupBy.String()[1:], "Count", "p50", "p90", "p99", "Max"}
+
+	return lipgloss.JoinVertical(lipgloss.Left, title, util.GenerateTable(headers, tableRows))
+}
+
+// cacheView renders the hit ratio and the count of each cache outcome
+func (m Model) cacheView() string {
+	s := m.stats
+	lines := []string{labelStyle.Render("Hit ratio") + valueStyle.Render(fmt.Sprintf("%.1f%%", s.HitRatio()*100))}
+	for _, o := range outcomes {
+		if count := s.Outcomes[o]; count > 0 {
+			lines = append(lines, labelStyle.Render("  "+o.String())+fmt.Sprintf("%d", count))
+		}
+	}
+	return lipgloss.JoinVertical(lipgloss.Left, lines...)
+}
+
+// statusView renders a bar for each status class of the requests
+//
+//	2xx ██████████████████████████████ 950  95.0%
+//	5xx ██                             50    5.0%
+func (m Model) statusView() string {
+	s := m.stats
+
+	var maxCount int
+	for _, c := range s.StatusClasses {
+		maxCount = max(maxCount, c)
+	}
+
+	lines := []string{styles.TitleStyle.Render("Status"), ""}
+	for class := 1; class <= 5; class++ {
+		lines = append(lines, statusLine(fmt.Sprintf("%dxx", class), s.StatusClasses[class], maxCount, s.Reqs, statusClassStyles[class]))
+	}
+	if s.StatusClasses[0] > 0 {
+		lines = append(lines, statusLine("-  ", s.StatusClasses[0], maxCount, s.Reqs, statusClassStyles[0]))
+	}
+	return lipgloss.JoinVertical(lipgloss.Left, lines...)
+}
+
+func statusLine(label string, count, maxCount, total int, style lipgloss.Style) string {
+	var length int
+	if maxCount > 0 {
+		length = count * barLength / maxCount
+	}
+	if count > 0 {
+		length = max(length, 1)
+	}
+	return fmt.Sprintf("%s %s %-8d %5.1f%%",
+		label,
+		style.Render(fmt.Sprintf("%-*s", barLength, strings.Repeat("█", length))),
+		count,
+		percent(count, total),
+	)
+}
+
+// topView renders the counters with their share of the requests
+func topView(title string, counters []tx.Counter, total, width int) string {
+	lines := []string{styles.TitleStyle.Render(title), ""}
+	if len(counters) == 0 {
+		lines = append(lines, styles.PagerStyle.Render("No requests yet"))
+	}
+	for _, c := range counters {
+		key := c.Key
+		if key == "" {
+			key = "-"
+		}
+		prefix := fmt.Sprintf("%8d %5.1f%% ", c.Count, percent(c.Count, total))
+		lines = append(lines, prefix+styles.UrlStyle.Render(util.TruncateString(key, max(width-len(prefix), 10))))
+	}
+	return lipgloss.JoinVertical(lipgloss.Left, lines...)
+}
+
+// histogramView renders the response time of the hits and the misses in log scale, the bars
+// take the whole width and height of the view
+func (m Model) histogramView() string {
+	title := styles.TitleStyle.Render("Response time") + styles.PagerStyle.Render(" · log scale")
+	if m.stats.Histogram.Total(tx.HistogramHits)+m.stats.Histogram.Total(tx.HistogramMisses) == 0 {
+		return lipgloss.JoinVertical(lipgloss.Left, title, "", styles.PagerStyle.Render("No timestamps yet"))
+	}
+
+	// Title, blank line, axis, labels and legend
+	height := m.viewport.Height - 5
+	return lipgloss.JoinVertical(lipgloss.Left, title, "", m.stats.Histogram.Render(m.width, height, []rune{'|', '#'}))
+}
+
+// rankingView renders the values of the chosen field with the highest decayed counts
+func (m Model) rankingView() string {
+	return m.ranking.view(m.width, m.viewport.Height)
+}
+
+func percent(count, total int) float64 {
+	if total == 0 {
+		return 0
+	}
+	return float64(count) * 100 / float64(total)
+}
+
+func switchToLogView() tea.Cmd {
+	return func() tea.Msg {
+		return state.ChangeModelState(state.LogView, "")
+	}
+}
@@ -0,0 +1,158 @@
+package dashboard
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/aorith/varnishlog-tui/internal/tx"
+	"github.com/aorith/varnishlog-tui/internal/ui/state"
+	"github.com/aorith/varnishlog-tui/internal/ui/styles"
+	"github.com/aorith/varnishlog-tui/internal/util"
+	"github.com/charmbracelet/bubbles/key"
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// rankingWindows are the decay periods of the counts, the default is a minute like varnishtop
+var rankingWindows = []time.Duration{10 * time.Second, time.Minute, 5 * time.Minute}
+
+// ranking shows the values of a field with the highest decayed counts, selecting one
+// filters the transactions view
+type ranking struct {
+	top     *tx.Top
+	field   int // Index of tx.TopFields, -1 for a field typed by the user
+	window  int // Index of rankingWindows
+	entries []tx.TopEntry
+	cursor  int
+	input   textinput.Model
+	editing bool
+	err     error
+}
+
+func newRanking() *ranking {
+	ti := textinput.New()
+	ti.Prompt = "field: "
+	ti.PromptStyle = styles.TitleStyle
+	ti.Placeholder = "resp.Content-Type"
+	ti.Cursor.Style = styles.NoStyle
+
+	r := &ranking{window: 1, input: ti}
+	r.top, _ = tx.NewTop(tx.TopFields[0], rankingWindows[r.window])
+	return r
+}
+
+func (r *ranking) add(t *tx.Tx) {
+	r.top.Add(t)
+}
+
+// reset starts counting again, with a new field or window
+func (r *ranking) reset(field string) error {
+	top, err := tx.NewTop(field, rankingWindows[r.window])
+	if err != nil {
+		return err
+	}
+	r.top = top
+	r.entries = nil
+	r.cursor = 0
+	return nil
+}
+
+// update handles the keys of the ranking, handled is false for the keys of the dashboard
+func (r *ranking) update(msg tea.KeyMsg) (handled bool, cmd tea.Cmd) {
+	if r.editing {
+		switch msg.String() {
+		case "esc":
+			r.editing = false
+			r.input.Blur()
+		case "enter":
+			field := strings.TrimSpace(r.input.Value())
+			if err := r.reset(field); err != nil {
+				r.err = err
+				return true, nil
+			}
+			r.field = -1
+			for i, f := range tx.TopFields {
+				if strings.EqualFold(f, field) {
+					r.field = i
+				}
+			}
+			r.editing = false
+			r.input.Blur()
+		default:
+			r.err = nil
+			r.input, cmd = r.input.Update(msg)
+		}
+		return true, cmd
+	}
+
+	switch {
+	case key.Matches(msg, keys.Up):
+		r.cursor = max(r.cursor-1, 0)
+	case key.Matches(msg, keys.Down):
+		r.cursor = min(r.cursor+1, max(len(r.entries)-1, 0))
+	case key.Matches(msg, keys.Field):
+		r.field = (r.field + 1) % len(tx.TopFields)
+		_ = r.reset(tx.TopFields[r.field])
+	case key.Matches(msg, keys.EditField):
+		r.editing = true
+		r.err = nil
+		r.input.SetValue(r.top.Field())
+		r.input.CursorEnd()
+		return true, r.input.Focus()
+	case key.Matches(msg, keys.Window):
+		r.window = (r.window + 1) % len(rankingWindows)
+		_ = r.reset(r.top.Field())
+	case key.Matches(msg, keys.Select):
+		if r.cursor < len(r.entries) {
+			return true, filterLogView(r.top.FilterExpr(r.entries[r.cursor].Key))
+		}
+	default:
+		return false, nil
+	}
+	return true, nil
+}
+
+// view renders the ranking in height lines, the entries shown are the ones that can be selected
+//
+//	Top url · decay 1m0s
+//
+//	Count  | url
+//	---------------------
+//	1203.4 | /api/items
+//	 820.1 | /index.html
+func (r *ranking) view(width, height int) string {
+	title := styles.TitleStyle.Render("Top "+r.top.Field()) +
+		styles.PagerStyle.Render(fmt.Sprintf(" · decay %s", r.top.Window()))
+	if r.editing {
+		hint := styles.PagerStyle.Render("fields: " + strings.Join(tx.FilterFields, " "))
+		if r.err != nil {
+			hint = styles.ErrorStyle.Render(r.err.Error())
+		}
+		r.input.Width = width - lipgloss.Width(r.input.Prompt) - 1
+		title = lipgloss.JoinVertical(lipgloss.Left, r.input.View(), lipgloss.NewStyle().MaxWidth(width).Render(hint))
+	}
+
+	// The table starts with a blank line, the headers and the separator
+	r.entries = r.top.Ranking(max(height-lipgloss.Height(title)-3, 1))
+	r.cursor = min(r.cursor, max(len(r.entries)-1, 0))
+	if len(r.entries) == 0 {
+		return lipgloss.JoinVertical(lipgloss.Left, title, "", styles.PagerStyle.Render("No values yet"))
+	}
+
+	rows := make([][]string, len(r.entries))
+	for i, e := range r.entries {
+		rows[i] = []string{fmt.Sprintf("%.1f", e.Count), util.TruncateString(e.Key, max(width-12, 10))}
+	}
+	lines := strings.Split(strings.TrimRight(util.GenerateTable([]string{"Count", r.top.Field()}, rows), "\n"), "\n")
+	lines[3+r.cursor] = styles.MatchedItemStyle.Render(lines[3+r.cursor])
+
+	return lipgloss.JoinVertical(lipgloss.Left, title, strings.Join(lines, "\n"))
+}
+
+func filterLogView(expr string) tea.Cmd {
+	return func() tea.Msg {
+		return state.ChangeModelState(state.LogView, state.NewFilterExprMsg(expr))
+	}
+}
@@ -0,0 +1,44 @@
+package flowview
+
+import (
+	"github.com/charmbracelet/bubbles/key"
+)
+
+// keyMap defines a set of keybindings. To work for help it must satisfy
+// key.Map. It could also very easily be a map[string]key.Binding.
+type keyMap struct {
+	Scroll key.Binding
+	Report key.Binding
+	Close  key.Binding
+}
+
+// ShortHelp returns keybindings to be shown in the mini help view. It's part
+// of the key.Map interface.
+func (k keyMap) ShortHelp() []key.Binding {
+	return []key.Binding{k.Scroll, k.Report, k.Close}
+}
+
+// FullHelp returns keybindings for the expanded help view. It's part of the
+// key.Map interface.
+func (k keyMap) FullHelp() [][]key.Binding {
+	return [][]key.Binding{
+		{k.Scroll},
+		{k.Report, k.Close},
+	}
+}
+
+var keys = keyMap{
+	// Handled by the viewport, only for the help
+	Scroll: key.NewBinding(
+		key.WithKeys("j", "k"),
+		key.WithHelp("j/k/pgup/pgdn", "scroll"),
+	),
+	Report: key.NewBinding(
+		key.WithKeys("w"),
+		key.WithHelp("w", "open HTML report in $BROWSER or $EDITOR"),
+	),
+	Close: key.NewBinding(
+		key.WithKeys("esc", "q"),
+		key.WithHelp("esc/q", "back to the list"),
+	),
+}
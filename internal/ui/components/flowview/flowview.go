@@ -0,0 +1,114 @@
+package flowview
+
+import (
+	"fmt"
+
+	"github.com/aorith/varnishlog-tui/internal/tx"
+	"github.com/aorith/varnishlog-tui/internal/ui/styles"
+	"github.com/aorith/varnishlog-tui/internal/util"
+	"github.com/charmbracelet/bubbles/help"
+	"github.com/charmbracelet/bubbles/key"
+	"github.com/charmbracelet/bubbles/viewport"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// Model shows the VCL transitions of the txs of the list aggregated in a table,
+// it is a snapshot of the txs visible when it was opened
+type Model struct {
+	txs      []*tx.Tx
+	flow     *tx.Flow
+	open     bool
+	viewport viewport.Model
+	help     help.Model
+	width    int
+	height   int
+}
+
+func New() Model {
+	return Model{
+		viewport: viewport.New(0, 0),
+		help:     help.New(),
+	}
+}
+
+// Open shows the flow of the txs
+func (m *Model) Open(txs []*tx.Tx) {
+	m.txs = txs
+	m.flow = tx.NewFlow(txs)
+	m.open = true
+	m.render()
+	m.viewport.GotoTop()
+}
+
+// IsOpen returns true until the flow is closed
+func (m Model) IsOpen() bool {
+	return m.open
+}
+
+// SetSize sets the size of the whole view
+func (m *Model) SetSize(width, height int) {
+	m.width, m.height = width, height
+	m.help.Width = width
+	m.viewport.Width = width
+	m.viewport.Height = max(height-lipgloss.Height(m.headerView())-lipgloss.Height(m.footerView()), 1)
+	if m.open {
+		m.render()
+	}
+}
+
+func (m Model) Update(msg tea.Msg) (Model, tea.Cmd) {
+	if keyMsg, ok := msg.(tea.KeyMsg); ok {
+		switch {
+		case key.Matches(keyMsg, keys.Close):
+			m.open = false
+			m.txs, m.flow = nil, nil
+			return m, nil
+		case key.Matches(keyMsg, keys.Report):
+			report, err := tx.GenerateFlowHtmlReport(m.txs)
+			if err != nil {
+				return m, func() tea.Msg { return util.EditorFinishedMsg{Err: err} }
+			}
+			return m, util.OpenInBrowserWithFallbackToEditor(report)
+		}
+	}
+
+	var cmd tea.Cmd
+	m.viewport, cmd = m.viewport.Update(msg)
+	return m, cmd
+}
+
+func (m Model) View() string {
+	return lipgloss.JoinVertical(lipgloss.Left, m.headerView(), m.viewport.View(), m.footerView())
+}
+
+func (m *Model) render() {
+	if m.flow == nil {
+		return
+	}
+	table := m.flow.GenerateFlowTable()
+	if table == "" {
+		table = styles.PagerStyle.Render("No VCL transitions in the listed txs")
+	}
+	m.viewport.SetContent(table)
+}
+
+// headerView renders the title with the number of txs
+//
+//	VCL flow · 950 of 1000 txs
+func (m Model) headerView() string {
+	var count string
+	if m.flow != nil {
+		count = fmt.Sprintf(" · %d of %d txs", m.flow.Txs, len(m.txs))
+	}
+	return lipgloss.JoinVertical(lipgloss.Left,
+		styles.TitleStyle.Render("VCL flow")+styles.PagerStyle.Render(count),
+		styles.PagerStyle.Render("Share of the transitions leaving each subroutine"),
+	)
+}
+
+// footerView renders the scroll position and the help
+func (m Model) footerView() string {
+	scroll := styles.PagerStyle.Render(fmt.Sprintf("%3.f%%", m.viewport.ScrollPercent()*100))
+	return lipgloss.JoinVertical(lipgloss.Left, "", scroll, m.help.View(keys))
+}
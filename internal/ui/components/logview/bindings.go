@@ -13,6 +13,10 @@ func additionalFullHelpKeys() []key.Binding {
 			key.WithKeys("d"),
 			key.WithHelp("d", "query loader"),
 		),
+		key.NewBinding(
+			key.WithKeys("D"),
+			key.WithHelp("D", "dashboard"),
+		),
 		key.NewBinding(
 			key.WithKeys("x"),
 			key.WithHelp("x", "clear transactions"),
@@ -39,7 +43,39 @@ func additionalFullHelpKeys() []key.Binding {
 		),
 		key.NewBinding(
 			key.WithKeys("enter"),
-			key.WithHelp("enter", "open HTML report in $BROWSER or $EDITOR"),
+			key.WithHelp("enter", "show tx details"),
+		),
+		key.NewBinding(
+			key.WithKeys("t"),
+			key.WithHelp("t", "navigate the tree of the tx"),
+		),
+		key.NewBinding(
+			key.WithKeys("v"),
+			key.WithHelp("v", "VCL flow of the visible txs"),
+		),
+		key.NewBinding(
+			key.WithKeys("w"),
+			key.WithHelp("w", "open HTML report in $BROWSER or $EDITOR"),
+		),
+		key.NewBinding(
+			key.WithKeys("o"),
+			key.WithHelp("o", "jump to the fetch that stored a hit object"),
+		),
+		key.NewBinding(
+			key.WithKeys("f"),
+			key.WithHelp("f", "filter by expression"),
+		),
+		key.NewBinding(
+			key.WithKeys("F"),
+			key.WithHelp("F", "filter by VSL query (-q)"),
+		),
+		key.NewBinding(
+			key.WithKeys("m"),
+			key.WithHelp("m", "cycle the sort mode"),
+		),
+		key.NewBinding(
+			key.WithKeys("S"),
+			key.WithHelp("S", "reopen the txs spilled to disk"),
 		),
 		key.NewBinding(
 			key.WithKeys("ctrl+c"),
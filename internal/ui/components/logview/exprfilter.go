@@ -0,0 +1,144 @@
+package logview
+
+import (
+	"strings"
+
+	"github.com/aorith/varnishlog-tui/internal/tx"
+	"github.com/aorith/varnishlog-tui/internal/ui/styles"
+	"github.com/aorith/varnishlog-tui/pkg/vsl"
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// exprFilterHeight is the number of lines taken by the expression editor
+const exprFilterHeight = 2
+
+// exprFilter edits and holds an expression that selects the txs of the list,
+// the fuzzy filter of the list is applied on top of it
+type exprFilter struct {
+	input   textinput.Model
+	editing bool
+	hint    string
+	parse   func(expr string) (func(t *tx.Tx) bool, error)
+	expr    string
+	matchFn func(t *tx.Tx) bool
+	err     error
+}
+
+// newExprFilter returns the editor of the typed filter expressions
+func newExprFilter() exprFilter {
+	return newFilterEditor(
+		"filter: ",
+		`status>=500 and host~"api" and duration>250ms and outcome=miss`,
+		"fields: "+strings.Join(tx.FilterFields, " "),
+		func(expr string) (func(t *tx.Tx) bool, error) {
+			filter, err := tx.ParseFilter(expr)
+			if err != nil {
+				return nil, err
+			}
+			return filter.Match, nil
+		},
+	)
+}
+
+// newVSLQueryFilter returns the editor of the VSL queries, evaluated on the whole group of each tx
+func newVSLQueryFilter() exprFilter {
+	return newFilterEditor(
+		"-q ",
+		`ReqURL ~ "^/api" and RespStatus >= 500`,
+		"varnishlog -q syntax: {level}taglist:prefix[field] op value, and or not, regular expressions are RE2",
+		func(expr string) (func(t *tx.Tx) bool, error) {
+			query, err := vsl.ParseQuery(expr)
+			if err != nil {
+				return nil, err
+			}
+			return func(t *tx.Tx) bool { return t.MatchQuery(query) }, nil
+		},
+	)
+}
+
+func newFilterEditor(prompt, placeholder, hint string, parse func(expr string) (func(t *tx.Tx) bool, error)) exprFilter {
+	ti := textinput.New()
+	ti.Prompt = prompt
+	ti.PromptStyle = styles.TitleStyle
+	ti.Placeholder = placeholder
+	ti.Cursor.Style = styles.NoStyle
+	return exprFilter{input: ti, hint: hint, parse: parse}
+}
+
+// start opens the editor with the current expression
+func (f *exprFilter) start() tea.Cmd {
+	f.editing = true
+	f.err = nil
+	f.input.SetValue(f.expr)
+	f.input.CursorEnd()
+	return f.input.Focus()
+}
+
+// update handles the keys while editing, applied is true when the filter changed
+func (f *exprFilter) update(msg tea.KeyMsg) (applied bool, cmd tea.Cmd) {
+	switch msg.String() {
+	case "esc":
+		f.stop()
+		return false, nil
+	case "enter":
+		if err := f.apply(f.input.Value()); err != nil {
+			f.err = err
+			return false, nil
+		}
+		f.stop()
+		return true, nil
+	}
+
+	f.input, cmd = f.input.Update(msg)
+	f.err = nil
+	if expr := strings.TrimSpace(f.input.Value()); expr != "" {
+		_, f.err = f.parse(expr)
+	}
+	return false, cmd
+}
+
+// apply sets the expression, an empty one removes the filter
+func (f *exprFilter) apply(expr string) error {
+	expr = strings.TrimSpace(expr)
+	if expr == "" {
+		f.expr, f.matchFn = "", nil
+		return nil
+	}
+	matchFn, err := f.parse(expr)
+	if err != nil {
+		return err
+	}
+	f.expr, f.matchFn = expr, matchFn
+	return nil
+}
+
+func (f *exprFilter) stop() {
+	f.editing = false
+	f.err = nil
+	f.input.Blur()
+}
+
+// active returns true if an expression is applied
+func (f exprFilter) active() bool {
+	return f.matchFn != nil
+}
+
+// match returns true if there is no expression or the tx matches it
+func (f exprFilter) match(t *tx.Tx) bool {
+	return f.matchFn == nil || f.matchFn(t)
+}
+
+// view renders the input and, below it, the parse error or the hint
+func (f exprFilter) view(width int) string {
+	f.input.Width = width - lipgloss.Width(f.input.Prompt) - 1
+
+	hint := styles.PagerStyle.Render(f.hint)
+	if f.err != nil {
+		hint = styles.ErrorStyle.Render(f.err.Error())
+	}
+	hint = lipgloss.NewStyle().MaxWidth(width).Render(hint)
+
+	return lipgloss.JoinVertical(lipgloss.Left, f.input.View(), hint)
+}
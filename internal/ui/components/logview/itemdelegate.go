@@ -8,8 +8,10 @@ import (
 
 	"github.com/aorith/varnishlog-tui/internal/tx"
 	"github.com/aorith/varnishlog-tui/internal/ui/styles"
+	"github.com/aorith/varnishlog-tui/pkg/vsl"
 	"github.com/charmbracelet/bubbles/list"
 	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
 	"github.com/muesli/reflow/truncate"
 )
 
@@ -33,7 +35,7 @@ type itemDelegate struct {
 // newDelegate creates a new itemDelegate
 func newDelegate() itemDelegate {
 	return itemDelegate{
-		height:  3,
+		height:  4,
 		spacing: 1,
 	}
 }
@@ -57,11 +59,12 @@ func (d itemDelegate) Update(msg tea.Msg, _ *list.Model) tea.Cmd {
 func (d itemDelegate) Render(w io.Writer, m list.Model, index int, listItem list.Item) {
 	var (
 		title, subtitle1, subtitle2 string
+		subtitle3                   string
 		parts                       []string
 		matchedRunes                []int
 	)
 
-	i, ok := listItem.(tx.Tx)
+	i, ok := listItem.(*tx.Tx)
 	if !ok {
 		return
 	}
@@ -86,28 +89,84 @@ func (d itemDelegate) Render(w io.Writer, m list.Model, index int, listItem list
 	list.NewDefaultDelegate()
 	parts = strings.Split(i.AsItem(matchedRunes, true, isFiltered && !emptyFilter), "\n")
 	title = parts[0]
+	if badge := outcomeBadge(i.CacheOutcome); badge != "" {
+		title = title + " " + badge
+	}
+	if badge := eventsBadge(i); badge != "" {
+		title = title + " " + badge
+	}
+	if i.Incomplete {
+		title = title + " " + styles.IncompleteBadgeStyle.Render(" incomplete ")
+	}
 	subtitle1 = parts[1]
 	subtitle2 = parts[2]
+	subtitle3 = parts[3]
 
 	// Prevent text from exceeding list width
 	textwidth := uint(m.Width() - styles.NormalItemStyle.GetPaddingLeft() - styles.NormalItemStyle.GetPaddingRight())
 	title = truncate.StringWithTail(title, textwidth, ellipsis)
 	subtitle1 = truncate.StringWithTail(subtitle1, textwidth, ellipsis)
 	subtitle2 = truncate.StringWithTail(subtitle2, textwidth, ellipsis)
+	subtitle3 = truncate.StringWithTail(subtitle3, textwidth, ellipsis)
 
 	if emptyFilter {
 		title = styles.DimmedItemStyle.Width(m.Width()).Render(title)
 		subtitle1 = styles.DimmedItemStyle.Width(m.Width()).Render(subtitle1)
 		subtitle2 = styles.DimmedItemStyle.Width(m.Width()).Render(subtitle2)
+		subtitle3 = styles.DimmedItemStyle.Width(m.Width()).Render(subtitle3)
 	} else if isSelected && m.FilterState() != list.Filtering {
 		title = styles.SelectedItemStyle.Width(m.Width()).Render(title)
 		subtitle1 = styles.SelectedItemStyle.Width(m.Width()).Render(subtitle1)
 		subtitle2 = styles.SelectedItemStyle.Width(m.Width()).Render(subtitle2)
+		subtitle3 = styles.SelectedItemStyle.Width(m.Width()).Render(subtitle3)
 	} else {
 		title = styles.NormalItemStyle.Width(m.Width()).Render(title)
 		subtitle1 = styles.NormalItemStyle.Width(m.Width()).Render(subtitle1)
 		subtitle2 = styles.NormalItemStyle.Width(m.Width()).Render(subtitle2)
+		subtitle3 = styles.NormalItemStyle.Width(m.Width()).Render(subtitle3)
 	}
 
-	fmt.Fprintf(w, "%s\n%s\n%s", title, subtitle1, subtitle2)
+	fmt.Fprintf(w, "%s\n%s\n%s\n%s", title, subtitle1, subtitle2, subtitle3)
+}
+
+// outcomeBadge returns a coloured badge with the cache outcome of the tx
+func outcomeBadge(outcome vsl.CacheOutcome) string {
+	var style lipgloss.Style
+	switch outcome {
+	case vsl.OutcomeHit:
+		style = styles.HitBadgeStyle
+	case vsl.OutcomeMiss:
+		style = styles.MissBadgeStyle
+	case vsl.OutcomePass:
+		style = styles.PassBadgeStyle
+	case vsl.OutcomePipe:
+		style = styles.PipeBadgeStyle
+	case vsl.OutcomeSynth:
+		style = styles.SynthBadgeStyle
+	case vsl.OutcomeHitMiss, vsl.OutcomeHitPass:
+		style = styles.HitForBadgeStyle
+	default:
+		return ""
+	}
+	return style.Render(" " + string(outcome) + " ")
+}
+
+// eventsBadge returns a badge with the number of errors and log lines of the tx
+func eventsBadge(t *tx.Tx) string {
+	errors, logs := t.CountEvents()
+	var badges []string
+	if errors > 0 {
+		badges = append(badges, styles.ErrorBadgeStyle.Render(fmt.Sprintf(" %d %s ", errors, plural(errors, "error", "errors"))))
+	}
+	if logs > 0 {
+		badges = append(badges, styles.LogBadgeStyle.Render(fmt.Sprintf(" %d %s ", logs, plural(logs, "log", "logs"))))
+	}
+	return strings.Join(badges, " ")
+}
+
+func plural(n int, singular, plural string) string {
+	if n == 1 {
+		return singular
+	}
+	return plural
 }
@@ -7,6 +7,9 @@ import (
 	"time"
 
 	"github.com/aorith/varnishlog-tui/internal/tx"
+	"github.com/aorith/varnishlog-tui/internal/ui/components/flowview"
+	"github.com/aorith/varnishlog-tui/internal/ui/components/txdetail"
+	"github.com/aorith/varnishlog-tui/internal/ui/components/txtree"
 	"github.com/aorith/varnishlog-tui/internal/ui/state"
 	"github.com/aorith/varnishlog-tui/internal/ui/styles"
 	"github.com/aorith/varnishlog-tui/internal/util"
@@ -14,6 +17,7 @@ import (
 	"github.com/charmbracelet/bubbles/paginator"
 	"github.com/charmbracelet/bubbles/spinner"
 	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
 	"github.com/charmbracelet/log"
 )
 
@@ -21,6 +25,9 @@ var frameHoriz, frameVert = styles.MainMarginStyle.GetFrameSize()
 
 type initFetchTxsMsg struct{}
 
+// evictTickMsg applies the retention limits while no txs arrive, so -max-age is met when idle
+type evictTickMsg struct{}
+
 type cancelTxsFetchMsg struct {
 	clear bool
 }
@@ -28,14 +35,28 @@ type cancelTxsFetchMsg struct {
 type Model struct {
 	list         list.Model
 	execSettings state.NewVarnishlogScriptMsg
-	txs          map[string]*tx.Tx
+	vslFile      string // Binary VSL file read instead of executing execSettings
+	txs          *tx.Store
+	retention    tx.Retention
 	fetching     bool
 	cancelChan   chan struct{}
 	txChan       chan tx.Tx
+	fetchStats   *tx.FetchStats
+	evictTicking bool
+	exprFilter   exprFilter
+	vslQuery     exprFilter
+	detail       txdetail.Model
+	tree         txtree.Model
+	flow         flowview.Model
+	width        int
+	height       int
 	err          error
+
+	// vxid of a hit origin not found in the buffer, pressing the key again queries it
+	pendingOriginVxid uint64
 }
 
-func New() Model {
+func New(retention tx.Retention) Model {
 	s := spinner.Spinner{
 		Frames: []string{"⣾", "⣽", "⣻", "⢿", "⡿", "⣟", "⣯", "⣷"},
 		FPS:    time.Second / 10,
@@ -50,7 +71,7 @@ func New() Model {
 	l.Paginator = p
 	l.SetSpinner(s)
 	l.SetShowTitle(true)
-	l.Title = "Transactions"
+	l.Title = listTitle(tx.SortByArrival)
 	l.Styles.Title = styles.TitleStyle
 	l.SetStatusBarItemName("tx", "txs")
 	l.SetShowStatusBar(true)
@@ -60,9 +81,15 @@ func New() Model {
 	l.AdditionalShortHelpKeys = additionalShortHelpKeys
 
 	return Model{
-		list:     l,
-		fetching: false,
-		txs:      make(map[string]*tx.Tx),
+		list:       l,
+		fetching:   false,
+		txs:        tx.NewStore(retention),
+		retention:  retention,
+		exprFilter: newExprFilter(),
+		vslQuery:   newVSLQueryFilter(),
+		detail:     txdetail.New(),
+		tree:       txtree.New(),
+		flow:       flowview.New(),
 	}
 }
 
@@ -78,17 +105,53 @@ func (m Model) Update(msg tea.Msg) (Model, tea.Cmd) {
 			m.err = nil
 		}
 
+		if m.detail.IsOpen() {
+			var cmd tea.Cmd
+			m.detail, cmd = m.detail.Update(msg)
+			return m, cmd
+		}
+
+		if m.tree.IsOpen() {
+			var cmd tea.Cmd
+			m.tree, cmd = m.tree.Update(msg)
+			return m, cmd
+		}
+
+		if m.flow.IsOpen() {
+			var cmd tea.Cmd
+			m.flow, cmd = m.flow.Update(msg)
+			return m, cmd
+		}
+
+		if editor := m.filterEditor(); editor != nil {
+			applied, cmd := editor.update(msg)
+			m.resizeList()
+			if applied {
+				m.updateTitle()
+				return m, tea.Batch(cmd, m.list.SetItems(m.sortedItems()), m.list.NewStatusMessage(
+					fmt.Sprintf("%d of %d txs match the filter", len(m.list.Items()), m.txs.Len()),
+				))
+			}
+			return m, cmd
+		}
+
 		// Don't match any of the keys below if we're filtering
 		if m.list.SettingFilter() {
 			break
 		}
 
 		key := msg.String()
+		if key != "o" {
+			m.pendingOriginVxid = 0
+		}
+
 		switch key {
 		case "q":
 			return m, tea.Sequence(m.CancelTxsFetchCmd(false), switchToQueryEditorView())
 		case "d":
 			return m, tea.Sequence(m.CancelTxsFetchCmd(false), switchToQueryLoaderView())
+		case "D":
+			return m, switchToDashboardView()
 		case "s":
 			return m, m.CancelTxsFetchCmd(false)
 		case "x":
@@ -101,7 +164,34 @@ func (m Model) Update(msg tea.Msg) (Model, tea.Cmd) {
 			return m, m.openEditorForCurrentAndRelatedTxsCmd()
 		case "ctrl+e":
 			return m, util.OpenEditor(m.getAllRawTx(), false, "txt")
+		case "o":
+			return m, m.jumpToHitOriginCmd()
+		case "S":
+			return m, m.reopenSpilledTxsCmd()
+		case "m":
+			return m, m.cycleSortModeCmd()
+		case "f":
+			cmd := m.exprFilter.start()
+			m.resizeList()
+			return m, cmd
+		case "F":
+			cmd := m.vslQuery.start()
+			m.resizeList()
+			return m, cmd
 		case "enter":
+			if currTx := m.getCurrentTx(); currTx != nil {
+				m.detail.Open(currTx)
+			}
+			return m, nil
+		case "t":
+			if currTx := m.getCurrentTx(); currTx != nil {
+				m.tree.Open(currTx)
+			}
+			return m, nil
+		case "v":
+			m.flow.Open(m.getVisibleTx())
+			return m, nil
+		case "w":
 			currTx := m.getCurrentTx()
 			if currTx != nil {
 				report, err := currTx.GenerateHtmlReport()
@@ -112,14 +202,20 @@ func (m Model) Update(msg tea.Msg) (Model, tea.Cmd) {
 				}
 			}
 		}
+	case txtree.OpenDetailsMsg:
+		m.detail.Open(msg.Tx)
+		return m, nil
 	case tea.WindowSizeMsg:
-		m.list.SetSize(msg.Width-frameHoriz, msg.Height-frameVert)
-	case tx.NewTxMsg:
-		if m.fetching {
-			newTx := tx.Tx(msg)
-			return m, tea.Batch(m.addNewTxCmd(newTx), tx.ListenForTxsCmd(m.txChan))
+		m.width, m.height = msg.Width-frameHoriz, msg.Height-frameVert
+		m.resizeList()
+	case tx.NewTxsMsg:
+		// Batches of a cleared or previous fetch are dropped
+		if msg.Source == m.txChan {
+			m.updateStatusBarStats()
+			return m, tea.Batch(m.addNewTxsCmd(msg.Txs), tx.ListenForTxsCmd(m.txChan))
 		}
 	case tx.FetchEndMsg:
+		m.updateStatusBarStats()
 		m.err = msg.Err
 		if m.err != nil {
 			log.Debug(m.err.Error())
@@ -131,21 +227,43 @@ func (m Model) Update(msg tea.Msg) (Model, tea.Cmd) {
 		}
 		m.fetching = false
 		m.list.StopSpinner()
+		m.cancelChan = make(chan struct{}) // reset the cancel channel to avoid errors on repeated 'c' press
 		if msg.clear {
-			m.txs = make(map[string]*tx.Tx)
+			mode := m.txs.SortMode()
+			m.txs = tx.NewStore(m.retention)
+			m.txs.SetSortMode(mode)
+			source := m.txChan
+			m.txChan = nil
+			return m, func() tea.Msg { return tx.TxsClearedMsg{Source: source} }
 		}
-		m.cancelChan = make(chan struct{}) // reset the cancel channel to avoid errors on repeated 'c' press
 	case initFetchTxsMsg:
 		if !m.fetching {
 			m.fetching = true
 			m.cancelChan = make(chan struct{})
-			m.txChan = make(chan tx.Tx)
+			m.txChan = make(chan tx.Tx, tx.MaxTxBatch)
+			m.fetchStats = &tx.FetchStats{}
+			m.updateStatusBarStats()
+			var tickCmd tea.Cmd
+			if m.retention.MaxAge > 0 && !m.evictTicking {
+				m.evictTicking = true
+				tickCmd = evictTickCmd()
+			}
 			return m, tea.Batch(
+				tickCmd,
 				m.list.StartSpinner(),
 				tx.ListenForTxsCmd(m.txChan),
-				tx.ExecVarnishlogAndFetchTxs(m.execSettings, m.cancelChan, m.txChan),
+				m.fetchSourceCmd(),
 			)
 		}
+	case evictTickMsg:
+		count := len(m.list.Items())
+		items, statusCmd := m.evict(slices.Clone(m.list.Items()))
+		if len(items) == count {
+			return m, tea.Batch(statusCmd, evictTickCmd())
+		}
+		m.detail.Refresh()
+		m.tree.Refresh(m.txs.Get)
+		return m, tea.Batch(m.list.SetItems(items), statusCmd, evictTickCmd())
 	case util.EditorFinishedMsg:
 		m.err = msg.Err
 	}
@@ -170,70 +288,315 @@ func (m Model) View() string {
 			)
 	}
 
+	if m.detail.IsOpen() {
+		return styles.MainMarginStyle.Render(m.detail.View())
+	}
+	if m.tree.IsOpen() {
+		return styles.MainMarginStyle.Render(m.tree.View())
+	}
+	if m.flow.IsOpen() {
+		return styles.MainMarginStyle.Render(m.flow.View())
+	}
+	if editor := m.filterEditor(); editor != nil {
+		return styles.MainMarginStyle.Render(
+			lipgloss.JoinVertical(lipgloss.Left, editor.view(m.width), m.list.View()),
+		)
+	}
 	return styles.MainMarginStyle.Render(m.list.View())
 }
 
+// filterEditor returns the expression editor being edited, if any
+func (m *Model) filterEditor() *exprFilter {
+	switch {
+	case m.exprFilter.editing:
+		return &m.exprFilter
+	case m.vslQuery.editing:
+		return &m.vslQuery
+	}
+	return nil
+}
+
+// resizeList leaves room for the expression editor while it is open
+func (m *Model) resizeList() {
+	height := m.height
+	if m.filterEditor() != nil {
+		height -= exprFilterHeight
+	}
+	m.list.SetSize(m.width, height)
+	m.detail.SetSize(m.width, m.height)
+	m.tree.SetSize(m.width, m.height)
+	m.flow.SetSize(m.width, m.height)
+}
+
 func switchToQueryEditorView() tea.Cmd {
 	return func() tea.Msg {
 		return state.ChangeModelState(state.QueryEditorView, "")
 	}
 }
 
+// SetFilterExpr applies an expression filter set from another view
+func (m *Model) SetFilterExpr(expr string) tea.Cmd {
+	if err := m.exprFilter.apply(expr); err != nil {
+		return m.list.NewStatusMessage(styles.ErrorStyle.Inline(true).Render(err.Error()))
+	}
+	m.updateTitle()
+	items := m.sortedItems()
+	return tea.Batch(m.list.SetItems(items), m.list.NewStatusMessage(
+		fmt.Sprintf("%d of %d txs match %s", len(items), m.txs.Len(), expr),
+	))
+}
+
+// switchToDashboardView shows the statistics, unlike the other views the fetch keeps running
+func switchToDashboardView() tea.Cmd {
+	return func() tea.Msg {
+		return state.ChangeModelState(state.DashboardView, "")
+	}
+}
+
 func switchToQueryLoaderView() tea.Cmd {
 	return func() tea.Msg {
 		return state.ChangeModelState(state.QueryLoaderView, "")
 	}
 }
 
-func (m *Model) addNewTxCmd(newTx tx.Tx) tea.Cmd {
-	m.txs[newTx.Txid] = &newTx
+// addNewTxsCmd stores a batch of txs and inserts them in the list keeping it sorted,
+// the list items are the stored txs so relationships linked later are shown too.
+// Only the new txs are matched against the filters, and with a VSL query the groups
+// they belong to, as their match depends on the rest of the group.
+func (m *Model) addNewTxsCmd(newTxs []tx.Tx) tea.Cmd {
+	var (
+		items  = slices.Clone(m.list.Items()) // Not modified in place behind the list
+		roots  = make(map[*tx.Tx]struct{})
+		update []*tx.Tx
+	)
+	for _, newTx := range newTxs {
+		if old := m.txs.Get(newTx.Txid); old != nil {
+			items = m.deleteItem(items, old)
+		}
+		t, _, _ := m.txs.Add(newTx)
+		if !m.vslQuery.active() {
+			update = append(update, t)
+			continue
+		}
+		group := t.QueryGroup()
+		if _, ok := roots[group[0]]; !ok {
+			roots[group[0]] = struct{}{}
+			update = append(update, group...)
+		}
+	}
+	for _, t := range update {
+		if m.exprFilter.match(t) && m.vslQuery.match(t) {
+			items = m.insertItem(items, t)
+		} else {
+			items = m.deleteItem(items, t)
+		}
+	}
+
+	items, statusCmd := m.evict(items)
+	m.detail.Refresh()
+	m.tree.Refresh(m.txs.Get)
 
-	// Update parent and children relationships
-	for _, currTx := range m.txs {
-		for childId := range currTx.Children {
-			child, childExists := m.txs[childId]
-			if childExists {
-				child.Parent = currTx
-				currTx.Children[childId] = child
-			}
+	return tea.Batch(m.list.SetItems(items), statusCmd)
+}
+
+// searchItem returns the index of the tx in the sorted items and whether it is listed
+func (m *Model) searchItem(items []list.Item, t *tx.Tx) (int, bool) {
+	return slices.BinarySearchFunc(items, t, func(item list.Item, t *tx.Tx) int {
+		return m.txs.Compare(item.(*tx.Tx), t)
+	})
+}
+
+// insertItem inserts the tx in the sorted items unless it is already listed
+func (m *Model) insertItem(items []list.Item, t *tx.Tx) []list.Item {
+	index, found := m.searchItem(items, t)
+	if found {
+		return items
+	}
+	return slices.Insert(items, index, list.Item(t))
+}
+
+// deleteItem removes the tx from the sorted items if it is listed
+func (m *Model) deleteItem(items []list.Item, t *tx.Tx) []list.Item {
+	if index, found := m.searchItem(items, t); found {
+		return slices.Delete(items, index, index+1)
+	}
+	return items
+}
+
+// evict applies the retention limits and removes the evicted txs from the items, which
+// are modified in place. The rest keep their order so the list is not rebuilt
+func (m *Model) evict(items []list.Item) ([]list.Item, tea.Cmd) {
+	var statusCmd tea.Cmd
+	evicted, err := m.txs.Evict(time.Now())
+	if err != nil {
+		log.Debug(err.Error())
+		statusCmd = m.list.NewStatusMessage(styles.ErrorStyle.Inline(true).Render(err.Error() + ", spill disabled"))
+	}
+	if len(evicted) == 0 {
+		return items, statusCmd
+	}
+
+	m.updateStatusBarStats()
+	gone := make(map[*tx.Tx]struct{}, len(evicted))
+	for _, t := range evicted {
+		gone[t] = struct{}{}
+	}
+	items = slices.DeleteFunc(items, func(item list.Item) bool {
+		t, ok := item.(*tx.Tx)
+		if !ok {
+			return false
 		}
+		_, ok = gone[t]
+		return ok
+	})
+	return items, statusCmd
+}
+
+func evictTickCmd() tea.Cmd {
+	return tea.Tick(time.Second, func(time.Time) tea.Msg {
+		return evictTickMsg{}
+	})
+}
+
+// cycleSortModeCmd sorts the list by the next sort mode keeping the selected tx
+func (m *Model) cycleSortModeCmd() tea.Cmd {
+	currTx := m.getCurrentTx()
+	m.txs.SetSortMode(m.txs.SortMode().Next())
+	m.updateTitle()
+
+	items := m.sortedItems()
+	cmd := m.list.SetItems(items)
+	if currTx != nil && !m.list.IsFiltered() {
+		if index := slices.Index(items, list.Item(currTx)); index >= 0 {
+			m.list.Select(index)
+		}
+	}
+	return cmd
+}
+
+// sortedItems returns the stored txs that match the expression filter and the VSL query
+// as list items in the current sort mode
+func (m *Model) sortedItems() []list.Item {
+	items := make([]list.Item, 0, m.txs.Len())
+	for _, t := range m.txs.Sorted() {
+		if m.exprFilter.match(t) && m.vslQuery.match(t) {
+			items = append(items, t)
+		}
+	}
+	return items
+}
+
+// updateTitle shows the sort mode and the expression filter in the title of the list
+func (m *Model) updateTitle() {
+	title := listTitle(m.txs.SortMode())
+	if m.exprFilter.active() {
+		title += " · " + util.TruncateString(m.exprFilter.expr, 60)
+	}
+	if m.vslQuery.active() {
+		title += " · -q " + util.TruncateString(m.vslQuery.expr, 60)
+	}
+	m.list.Title = title
+}
+
+func listTitle(mode tx.SortMode) string {
+	return fmt.Sprintf("Transactions · by %s", mode)
+}
+
+// reopenSpilledTxsCmd opens a query in the query editor that reads the txs spilled to disk
+func (m *Model) reopenSpilledTxsCmd() tea.Cmd {
+	spill := m.txs.Spill()
+	if spill == nil {
+		return m.list.NewStatusMessage("No txs have been spilled to disk")
 	}
 
-	// Extract and sort the keys
-	keys := make([]string, 0, len(m.txs))
-	for k := range m.txs {
-		keys = append(keys, m.txs[k].Txid)
+	segments, err := spill.Seal()
+	if err != nil {
+		m.err = err
+		return nil
+	}
+
+	quoted := make([]string, 0, len(segments))
+	for _, segment := range segments {
+		quoted = append(quoted, "'"+strings.ReplaceAll(segment, "'", `'\''`)+"'")
+	}
+	script := fmt.Sprintf(
+		"# %d txs evicted from the buffer and spilled to %s\n\ncat \\\n%s",
+		spill.Len(),
+		m.retention.SpillDir,
+		strings.Join(quoted, " \\\n"),
+	)
+	return tea.Sequence(m.CancelTxsFetchCmd(false), func() tea.Msg {
+		return state.ChangeModelState(state.QueryEditorView, state.NewQueryEditorScriptMsg(script))
+	})
+}
+
+// jumpToHitOriginCmd selects the bereq that stored the object served by the current tx.
+// If it is not in the buffer, pressing the key again opens a query for it in the query editor.
+func (m *Model) jumpToHitOriginCmd() tea.Cmd {
+	currTx := m.getCurrentTx()
+	if currTx == nil || currTx.HitVxid == 0 {
+		return m.list.NewStatusMessage("The current tx did not hit a cached object")
 	}
-	slices.Sort(keys)
 
-	// Create a sorted slice of list.Item
-	items := make([]list.Item, 0, len(m.txs))
-	for _, k := range keys {
-		items = append(items, *m.txs[k])
+	if currTx.HitTx == nil {
+		if m.pendingOriginVxid == currTx.HitVxid {
+			m.pendingOriginVxid = 0
+			script := fmt.Sprintf(
+				"# Backend fetch that stored the object served by tx %s\n"+
+					"# -d reads the whole shared memory log, the tx may have been overwritten already\n\n"+
+					"varnishlog -d -g request -q 'vxid == %d'",
+				currTx.Txid,
+				currTx.HitVxid,
+			)
+			return tea.Sequence(m.CancelTxsFetchCmd(false), func() tea.Msg {
+				return state.ChangeModelState(state.QueryEditorView, state.NewQueryEditorScriptMsg(script))
+			})
+		}
+		m.pendingOriginVxid = currTx.HitVxid
+		if spill := m.txs.Spill(); spill != nil && spill.Contains(currTx.HitVxid) {
+			return m.list.NewStatusMessage(fmt.Sprintf("Tx %d may have been evicted, press S to reopen the spilled txs or o to query it", currTx.HitVxid))
+		}
+		return m.list.NewStatusMessage(fmt.Sprintf("Tx %d is not in the buffer, press o again to query it", currTx.HitVxid))
+	}
+
+	originId := currTx.HitTx.Txid
+	for i, item := range m.list.VisibleItems() {
+		if t, ok := item.(*tx.Tx); ok && t.Txid == originId {
+			m.list.Select(i)
+			return nil
+		}
 	}
 
-	return m.list.SetItems(items)
+	// Hidden by the fuzzy filter, the items only hold the txs that match the expression filters
+	for i, item := range m.list.Items() {
+		if t, ok := item.(*tx.Tx); ok && t.Txid == originId {
+			m.list.ResetFilter()
+			m.list.Select(i)
+			return nil
+		}
+	}
+	return m.list.NewStatusMessage(fmt.Sprintf("Tx %s is hidden by the expression filter or the VSL query", originId))
 }
 
 func (m *Model) getCurrentTx() *tx.Tx {
-	currTx, ok := m.list.SelectedItem().(tx.Tx)
+	currTx, ok := m.list.SelectedItem().(*tx.Tx)
 	if !ok {
 		return nil
 	}
-	return &currTx
+	return currTx
 }
 
 // getVisibleTx gets all the visible txs
-func (m *Model) getVisibleTx() []tx.Tx {
+func (m *Model) getVisibleTx() []*tx.Tx {
 	var (
-		currTx       tx.Tx
+		currTx       *tx.Tx
 		ok           bool
 		visibleItems = m.list.VisibleItems()
 	)
 
-	visibleTxs := make([]tx.Tx, 0, len(visibleItems))
+	visibleTxs := make([]*tx.Tx, 0, len(visibleItems))
 	for _, i := range visibleItems {
-		currTx, ok = i.(tx.Tx)
+		currTx, ok = i.(*tx.Tx)
 		if ok {
 			visibleTxs = append(visibleTxs, currTx)
 		}
@@ -241,18 +604,10 @@ func (m *Model) getVisibleTx() []tx.Tx {
 	return visibleTxs
 }
 
-// getAllRawTx gets the rawTxs of all the visible txs
+// getAllRawTx gets the rawTxs of all the visible txs in the order of the list
 func (m *Model) getAllRawTx() (rawTxs []string) {
-	visibleTxs := m.getVisibleTx()
-
-	keys := make([]string, 0, len(visibleTxs))
-	for _, v := range visibleTxs {
-		keys = append(keys, v.Txid)
-	}
-	slices.Sort(keys)
-
-	for _, k := range keys {
-		rawTxs = append(rawTxs, m.txs[k].RawTx...)
+	for _, t := range m.getVisibleTx() {
+		rawTxs = append(rawTxs, t.RawTx...)
 		rawTxs = append(rawTxs, "") // New line
 	}
 	return rawTxs
@@ -269,6 +624,20 @@ func (m *Model) openEditorForCurrentTxCmd() tea.Cmd {
 	finalText = append(finalText, "Tx Duration", "===========")
 	finalText = append(finalText, strings.Split(currTx.GenerateTimestampHistogram(), "\n")...)
 
+	if client := currTx.ClientSummary(); client != "" {
+		finalText = append(finalText, "", "Client", "======", "", client)
+	}
+
+	if events := currTx.GenerateEventsTable(); events != "" {
+		finalText = append(finalText, "", "Events", "======")
+		finalText = append(finalText, strings.Split(events, "\n")...)
+	}
+
+	if headers := currTx.GenerateHeadersTables(); headers != "" {
+		finalText = append(finalText, "", "Headers", "=======")
+		finalText = append(finalText, strings.Split(headers, "\n")...)
+	}
+
 	finalText = append(finalText, "", "Raw log", "=======", "")
 	finalText = append(finalText, currTx.RawTx...)
 	finalText = append(finalText, "")
@@ -303,6 +672,25 @@ func (m *Model) openEditorForCurrentAndRelatedTxsCmd() tea.Cmd {
 	finalText = append(finalText, "", "Txs Transmitted Accounting", "==========================")
 	finalText = append(finalText, strings.Split(acctTransmitted, "\n")...)
 
+	finalText = append(finalText, "", "Txs Header Changes", "==================")
+	for _, tx := range txs {
+		if changes := tx.GenerateHeaderChangesTable(); changes != "" {
+			finalText = append(finalText, "", fmt.Sprintf("Tx %s", tx.Txid))
+			for _, sc := range tx.HeaderTimeline() {
+				finalText = append(finalText, sc.String())
+			}
+			finalText = append(finalText, strings.Split(changes, "\n")...)
+		}
+	}
+
+	finalText = append(finalText, "", "Txs Headers", "===========")
+	for _, tx := range txs {
+		if headers := tx.GenerateHeadersTables(); headers != "" {
+			finalText = append(finalText, "", fmt.Sprintf("Tx %s", tx.Txid))
+			finalText = append(finalText, strings.Split(headers, "\n")...)
+		}
+	}
+
 	finalText = append(finalText, "", "Raw log", "=======", "")
 	for _, tx := range txs {
 		finalText = append(finalText, tx.RawTx...)
@@ -314,6 +702,43 @@ func (m *Model) openEditorForCurrentAndRelatedTxsCmd() tea.Cmd {
 
 func (m *Model) SetVarnishlogExecSettings(execSettings state.NewVarnishlogScriptMsg) {
 	m.execSettings = execSettings
+	m.vslFile = ""
+}
+
+// SetVSLFile sets a binary VSL file to be read by the next fetch instead of executing a script
+func (m *Model) SetVSLFile(path string) {
+	m.vslFile = path
+}
+
+func (m *Model) fetchSourceCmd() tea.Cmd {
+	if m.vslFile != "" {
+		return tx.ReadVSLFileAndFetchTxs(m.vslFile, m.cancelChan, m.txChan, m.fetchStats)
+	}
+	return tx.ExecVarnishlogAndFetchTxs(m.execSettings, m.cancelChan, m.txChan, m.fetchStats)
+}
+
+// updateStatusBarStats shows the txs of the fetch that could not be fully parsed next to the number of txs
+func (m *Model) updateStatusBarStats() {
+	var suffix string
+	if m.fetchStats != nil {
+		stats := m.fetchStats.Load()
+		if stats.Incomplete > 0 {
+			suffix += fmt.Sprintf(" · %d incomplete", stats.Incomplete)
+		}
+		if stats.Malformed > 0 {
+			suffix += fmt.Sprintf(" · %d malformed", stats.Malformed)
+		}
+		if stats.Skipped > 0 {
+			suffix += fmt.Sprintf(" · %d skipped", stats.Skipped)
+		}
+	}
+	if evicted := m.txs.Evicted(); evicted > 0 {
+		suffix += fmt.Sprintf(" · %d evicted", evicted)
+		if spill := m.txs.Spill(); spill != nil {
+			suffix += fmt.Sprintf(" · %d spilled", spill.Len())
+		}
+	}
+	m.list.SetStatusBarItemName("tx"+suffix, "txs"+suffix)
 }
 
 func (m *Model) FetchTxsCmd() tea.Cmd {
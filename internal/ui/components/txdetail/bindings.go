@@ -0,0 +1,49 @@
+package txdetail
+
+import (
+	"github.com/charmbracelet/bubbles/key"
+)
+
+// keyMap defines a set of keybindings. To work for help it must satisfy
+// key.Map. It could also very easily be a map[string]key.Binding.
+type keyMap struct {
+	NextTab key.Binding
+	PrevTab key.Binding
+	Scroll  key.Binding
+	Close   key.Binding
+}
+
+// ShortHelp returns keybindings to be shown in the mini help view. It's part
+// of the key.Map interface.
+func (k keyMap) ShortHelp() []key.Binding {
+	return []key.Binding{k.NextTab, k.PrevTab, k.Scroll, k.Close}
+}
+
+// FullHelp returns keybindings for the expanded help view. It's part of the
+// key.Map interface.
+func (k keyMap) FullHelp() [][]key.Binding {
+	return [][]key.Binding{
+		{k.NextTab, k.PrevTab},
+		{k.Scroll, k.Close},
+	}
+}
+
+var keys = keyMap{
+	NextTab: key.NewBinding(
+		key.WithKeys("tab", "l", "right"),
+		key.WithHelp("tab/l", "next tab"),
+	),
+	PrevTab: key.NewBinding(
+		key.WithKeys("shift+tab", "h", "left"),
+		key.WithHelp("shift+tab/h", "previous tab"),
+	),
+	// Handled by the viewport, only for the help
+	Scroll: key.NewBinding(
+		key.WithKeys("j", "k"),
+		key.WithHelp("j/k/pgup/pgdn", "scroll"),
+	),
+	Close: key.NewBinding(
+		key.WithKeys("esc", "q"),
+		key.WithHelp("esc/q", "back to the list"),
+	),
+}
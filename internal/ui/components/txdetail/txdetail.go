@@ -0,0 +1,197 @@
+package txdetail
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/aorith/varnishlog-tui/internal/tx"
+	"github.com/aorith/varnishlog-tui/internal/ui/styles"
+	"github.com/charmbracelet/bubbles/help"
+	"github.com/charmbracelet/bubbles/key"
+	"github.com/charmbracelet/bubbles/viewport"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// tab is a section of the details of a tx
+type tab struct {
+	name   string
+	render func(t *tx.Tx) string
+}
+
+var tabs = []tab{
+	{"Summary", renderSummary},
+	{"Timestamps", func(t *tx.Tx) string { return t.GenerateTimestampHistogram() }},
+	{"Waterfall", func(t *tx.Tx) string { return orNone(t.GenerateWaterfall(), "No timestamps") }},
+	{"VCL", renderVCL},
+	{"Headers", renderHeaders},
+	{"TTL", func(t *tx.Tx) string { return orNone(t.GenerateTTLTable(), "No TTL records") }},
+	{"Raw", func(t *tx.Tx) string { return strings.Join(t.RawTx, "\n") }},
+}
+
+// Model shows the details of a tx in scrollable tabs without leaving the TUI
+type Model struct {
+	tx       *tx.Tx
+	open     bool
+	active   int
+	offsets  []int // Scroll position of each tab
+	viewport viewport.Model
+	help     help.Model
+	width    int
+	height   int
+}
+
+func New() Model {
+	return Model{
+		viewport: viewport.New(0, 0),
+		help:     help.New(),
+		offsets:  make([]int, len(tabs)),
+	}
+}
+
+// Open shows the details of the tx starting on the summary
+func (m *Model) Open(t *tx.Tx) {
+	m.tx = t
+	m.open = true
+	m.active = 0
+	m.offsets = make([]int, len(tabs))
+	m.render()
+}
+
+// IsOpen returns true until the details are closed
+func (m Model) IsOpen() bool {
+	return m.open
+}
+
+// Refresh renders the tx again keeping the scroll, its relationships may have changed
+func (m *Model) Refresh() {
+	if m.open {
+		m.offsets[m.active] = m.viewport.YOffset
+		m.render()
+	}
+}
+
+// SetSize sets the size of the whole view
+func (m *Model) SetSize(width, height int) {
+	m.width, m.height = width, height
+	m.help.Width = width
+	m.viewport.Width = width
+	m.viewport.Height = max(height-lipgloss.Height(m.headerView())-lipgloss.Height(m.footerView()), 1)
+	if m.open {
+		m.offsets[m.active] = m.viewport.YOffset
+		m.render()
+	}
+}
+
+func (m Model) Update(msg tea.Msg) (Model, tea.Cmd) {
+	if keyMsg, ok := msg.(tea.KeyMsg); ok {
+		switch {
+		case key.Matches(keyMsg, keys.Close):
+			m.open = false
+			return m, nil
+		case key.Matches(keyMsg, keys.NextTab):
+			m.switchTab((m.active + 1) % len(tabs))
+			return m, nil
+		case key.Matches(keyMsg, keys.PrevTab):
+			m.switchTab((m.active + len(tabs) - 1) % len(tabs))
+			return m, nil
+		}
+		if n, err := strconv.Atoi(keyMsg.String()); err == nil && n >= 1 && n <= len(tabs) {
+			m.switchTab(n - 1)
+			return m, nil
+		}
+	}
+
+	var cmd tea.Cmd
+	m.viewport, cmd = m.viewport.Update(msg)
+	return m, cmd
+}
+
+func (m Model) View() string {
+	return lipgloss.JoinVertical(lipgloss.Left, m.headerView(), m.viewport.View(), m.footerView())
+}
+
+func (m *Model) switchTab(i int) {
+	m.offsets[m.active] = m.viewport.YOffset
+	m.active = i
+	m.render()
+}
+
+// render sets the content of the active tab restoring its scroll position
+func (m *Model) render() {
+	if m.tx == nil {
+		return
+	}
+	m.viewport.SetContent(tabs[m.active].render(m.tx))
+	m.viewport.SetYOffset(m.offsets[m.active])
+}
+
+// headerView renders the title and the tabs
+//
+//	Tx 32770 req rxreq
+//	1 Summary │ 2 Timestamps │ 3 Waterfall │ ...
+func (m Model) headerView() string {
+	var title string
+	if m.tx != nil {
+		title = fmt.Sprintf("Tx %s %s %s", m.tx.Txid, m.tx.RecordType, m.tx.Reason)
+	}
+
+	names := make([]string, len(tabs))
+	for i, t := range tabs {
+		name := fmt.Sprintf("%d %s", i+1, t.name)
+		if i == m.active {
+			names[i] = styles.TitleStyle.Underline(true).Render(name)
+		} else {
+			names[i] = styles.PagerStyle.Render(name)
+		}
+	}
+
+	return lipgloss.JoinVertical(lipgloss.Left,
+		styles.TitleStyle.Render(title),
+		lipgloss.NewStyle().MaxWidth(m.width).Render(strings.Join(names, styles.PagerStyle.Render(" │ "))),
+		"",
+	)
+}
+
+// footerView renders the scroll position and the help
+func (m Model) footerView() string {
+	scroll := styles.PagerStyle.Render(fmt.Sprintf("%3.f%%", m.viewport.ScrollPercent()*100))
+	return lipgloss.JoinVertical(lipgloss.Left, "", scroll, m.help.View(keys))
+}
+
+func renderSummary(t *tx.Tx) string {
+	root := t.FindRootParent()
+	s := []string{
+		t.GenerateSummary(),
+		"Txs Tree",
+		"",
+		root.PrintTree("", t.Txid, false),
+	}
+	if client := t.ClientSummary(); client != "" {
+		s = append(s, "Client", "", client, "")
+	}
+	if events := t.GenerateEventsTable(); events != "" {
+		s = append(s, "Events", events)
+	}
+	return strings.Join(s, "\n")
+}
+
+func renderVCL(t *tx.Tx) string {
+	s := []string{"VCL transitions", orNone(t.GenerateTransitionsTable(), "\nNo VCL transitions")}
+	if changes := t.GenerateHeaderChangesTable(); changes != "" {
+		s = append(s, "", "Header changes", changes)
+	}
+	return strings.Join(s, "\n")
+}
+
+func renderHeaders(t *tx.Tx) string {
+	return orNone(strings.TrimPrefix(t.GenerateHeadersTables(), "\n"), "No headers")
+}
+
+func orNone(s, none string) string {
+	if s == "" {
+		return none
+	}
+	return s
+}
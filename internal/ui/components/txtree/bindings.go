@@ -0,0 +1,85 @@
+package txtree
+
+import (
+	"github.com/charmbracelet/bubbles/key"
+)
+
+// keyMap defines a set of keybindings. To work for help it must satisfy
+// key.Map. It could also very easily be a map[string]key.Binding.
+type keyMap struct {
+	Up          key.Binding
+	Down        key.Binding
+	Parent      key.Binding
+	Child       key.Binding
+	PrevSibling key.Binding
+	NextSibling key.Binding
+	Toggle      key.Binding
+	ExpandAll   key.Binding
+	CollapseAll key.Binding
+	Details     key.Binding
+	Close       key.Binding
+}
+
+// ShortHelp returns keybindings to be shown in the mini help view. It's part
+// of the key.Map interface.
+func (k keyMap) ShortHelp() []key.Binding {
+	return []key.Binding{k.Up, k.Down, k.Parent, k.Child, k.NextSibling, k.PrevSibling, k.Toggle, k.Details, k.Close}
+}
+
+// FullHelp returns keybindings for the expanded help view. It's part of the
+// key.Map interface.
+func (k keyMap) FullHelp() [][]key.Binding {
+	return [][]key.Binding{
+		{k.Up, k.Down, k.Parent, k.Child},
+		{k.PrevSibling, k.NextSibling},
+		{k.Toggle, k.ExpandAll, k.CollapseAll},
+		{k.Details, k.Close},
+	}
+}
+
+var keys = keyMap{
+	Up: key.NewBinding(
+		key.WithKeys("k", "up"),
+		key.WithHelp("↑/k", "up"),
+	),
+	Down: key.NewBinding(
+		key.WithKeys("j", "down"),
+		key.WithHelp("↓/j", "down"),
+	),
+	Parent: key.NewBinding(
+		key.WithKeys("h", "left"),
+		key.WithHelp("←/h", "collapse/parent"),
+	),
+	Child: key.NewBinding(
+		key.WithKeys("l", "right"),
+		key.WithHelp("→/l", "expand/child"),
+	),
+	PrevSibling: key.NewBinding(
+		key.WithKeys("K", "shift+up"),
+		key.WithHelp("K", "previous sibling"),
+	),
+	NextSibling: key.NewBinding(
+		key.WithKeys("J", "shift+down"),
+		key.WithHelp("J", "next sibling"),
+	),
+	Toggle: key.NewBinding(
+		key.WithKeys(" "),
+		key.WithHelp("space", "toggle"),
+	),
+	ExpandAll: key.NewBinding(
+		key.WithKeys("E"),
+		key.WithHelp("E", "expand all"),
+	),
+	CollapseAll: key.NewBinding(
+		key.WithKeys("C"),
+		key.WithHelp("C", "collapse all"),
+	),
+	Details: key.NewBinding(
+		key.WithKeys("enter"),
+		key.WithHelp("enter", "details"),
+	),
+	Close: key.NewBinding(
+		key.WithKeys("esc", "q"),
+		key.WithHelp("esc/q", "back to the list"),
+	),
+}
@@ -0,0 +1,304 @@
+package txtree
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/aorith/varnishlog-tui/internal/tx"
+	"github.com/aorith/varnishlog-tui/internal/ui/styles"
+	"github.com/aorith/varnishlog-tui/internal/util"
+	"github.com/charmbracelet/bubbles/help"
+	"github.com/charmbracelet/bubbles/key"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// OpenDetailsMsg is sent when the details of a node of the tree are requested
+type OpenDetailsMsg struct {
+	Tx *tx.Tx
+}
+
+// node is a visible row of the tree
+type node struct {
+	tx     *tx.Tx
+	parent *node
+	prefix string // Guides of the ancestors and the connector of this node
+}
+
+// Model shows the group of a tx as a tree of expandable nodes
+//
+//	▾ 32769 sess new 192.168.50.1:50312
+//	├── ▾ 32770 req rxreq 200 GET www.example.com/ 1.2ms
+//	│   └──   32771 bereq fetch (not received)
+//	└── ▸ 32772 req esi 200 GET www.example.com/esi 310µs (+3)
+type Model struct {
+	root      *tx.Tx
+	open      bool
+	selected  string          // Txid of the node under the cursor
+	collapsed map[string]bool // Txids of the collapsed nodes
+	nodes     []*node
+	cursor    int
+	offset    int // First visible node
+	status    string
+	help      help.Model
+	width     int
+	height    int
+}
+
+func New() Model {
+	return Model{
+		help:      help.New(),
+		collapsed: make(map[string]bool),
+	}
+}
+
+// Open shows the tree of the group of the tx with the cursor on it
+func (m *Model) Open(t *tx.Tx) {
+	m.root = t.FindRootParent()
+	m.open = true
+	m.selected = t.Txid
+	m.collapsed = make(map[string]bool)
+	m.offset = 0
+	m.status = ""
+	m.build()
+}
+
+// IsOpen returns true until the tree is closed
+func (m Model) IsOpen() bool {
+	return m.open
+}
+
+// Refresh rebuilds the tree, children received after it was opened replace their placeholders.
+// get returns the stored tx of a txid, the root is looked up again in case it was replaced
+func (m *Model) Refresh(get func(txid string) *tx.Tx) {
+	if !m.open {
+		return
+	}
+	if root := get(m.root.Txid); root != nil {
+		m.root = root.FindRootParent()
+	}
+	m.build()
+}
+
+// SetSize sets the size of the whole view
+func (m *Model) SetSize(width, height int) {
+	m.width, m.height = width, height
+	m.help.Width = width
+	m.scroll()
+}
+
+func (m Model) Update(msg tea.Msg) (Model, tea.Cmd) {
+	keyMsg, ok := msg.(tea.KeyMsg)
+	if !ok || len(m.nodes) == 0 {
+		return m, nil
+	}
+
+	m.status = ""
+	curr := m.nodes[m.cursor]
+
+	switch {
+	case key.Matches(keyMsg, keys.Close):
+		m.open = false
+	case key.Matches(keyMsg, keys.Up):
+		m.moveTo(m.cursor - 1)
+	case key.Matches(keyMsg, keys.Down):
+		m.moveTo(m.cursor + 1)
+	case key.Matches(keyMsg, keys.Parent):
+		if m.expanded(curr.tx) {
+			m.setCollapsed(curr.tx, true)
+		} else if curr.parent != nil {
+			m.selectTx(curr.parent.tx)
+		}
+	case key.Matches(keyMsg, keys.Child):
+		if len(curr.tx.Children) == 0 {
+			break
+		}
+		if !m.expanded(curr.tx) {
+			m.setCollapsed(curr.tx, false)
+		} else {
+			m.selectTx(curr.tx.DirectChildren()[0])
+		}
+	case key.Matches(keyMsg, keys.PrevSibling):
+		m.moveToSibling(curr, -1)
+	case key.Matches(keyMsg, keys.NextSibling):
+		m.moveToSibling(curr, 1)
+	case key.Matches(keyMsg, keys.Toggle):
+		if len(curr.tx.Children) > 0 {
+			m.setCollapsed(curr.tx, m.expanded(curr.tx))
+		}
+	case key.Matches(keyMsg, keys.ExpandAll):
+		m.collapsed = make(map[string]bool)
+		m.build()
+	case key.Matches(keyMsg, keys.CollapseAll):
+		for _, n := range m.nodes {
+			if n.parent != nil && len(n.tx.Children) > 0 {
+				m.collapsed[n.tx.Txid] = true
+			}
+		}
+		// The cursor goes up to the nearest visible ancestor
+		for curr.parent != nil && m.collapsed[curr.parent.tx.Txid] {
+			curr = curr.parent
+		}
+		m.selected = curr.tx.Txid
+		m.build()
+	case key.Matches(keyMsg, keys.Details):
+		if !curr.tx.Received() {
+			m.status = fmt.Sprintf("tx %s was linked but never received", curr.tx.Txid)
+			break
+		}
+		t := curr.tx
+		return m, func() tea.Msg { return OpenDetailsMsg{Tx: t} }
+	}
+
+	return m, nil
+}
+
+func (m Model) View() string {
+	header := lipgloss.JoinVertical(lipgloss.Left, styles.TitleStyle.Render("Txs Tree"), "")
+
+	status := styles.PagerStyle.Render(fmt.Sprintf("%d of %d txs", m.cursor+1, len(m.nodes)))
+	if m.status != "" {
+		status = styles.ErrorStyle.Render(m.status)
+	}
+	footer := lipgloss.JoinVertical(lipgloss.Left, "", status, m.help.View(keys))
+
+	var rows []string
+	end := min(m.offset+m.visibleRows(), len(m.nodes))
+	for i := m.offset; i < end; i++ {
+		line := lipgloss.NewStyle().MaxWidth(max(m.width-2, 1)).Render(m.renderNode(m.nodes[i]))
+		if i == m.cursor {
+			rows = append(rows, styles.SelectedItemStyle.Render(line))
+		} else {
+			rows = append(rows, styles.NormalItemStyle.Render(line))
+		}
+	}
+	body := lipgloss.NewStyle().Height(m.visibleRows()).Render(strings.Join(rows, "\n"))
+
+	return lipgloss.JoinVertical(lipgloss.Left, header, body, footer)
+}
+
+// build flattens the expanded nodes of the tree and places the cursor on the selected tx
+func (m *Model) build() {
+	m.nodes = nil
+	if m.root == nil {
+		return
+	}
+	m.appendNode(m.root, nil, "", "")
+
+	m.cursor = slices.IndexFunc(m.nodes, func(n *node) bool { return n.tx.Txid == m.selected })
+	if m.cursor < 0 {
+		m.cursor = 0
+		m.selected = m.root.Txid
+	}
+	m.scroll()
+}
+
+// appendNode appends the node and its children when expanded, indent is the prefix of its children
+func (m *Model) appendNode(t *tx.Tx, parent *node, prefix, indent string) {
+	n := &node{tx: t, parent: parent, prefix: prefix}
+	m.nodes = append(m.nodes, n)
+	if !m.expanded(t) {
+		return
+	}
+
+	children := t.DirectChildren()
+	for i, child := range children {
+		if i == len(children)-1 {
+			m.appendNode(child, n, indent+"└── ", indent+"    ")
+		} else {
+			m.appendNode(child, n, indent+"├── ", indent+"│   ")
+		}
+	}
+}
+
+func (m Model) expanded(t *tx.Tx) bool {
+	return len(t.Children) > 0 && !m.collapsed[t.Txid]
+}
+
+func (m *Model) setCollapsed(t *tx.Tx, collapsed bool) {
+	m.collapsed[t.Txid] = collapsed
+	m.selected = t.Txid
+	m.build()
+}
+
+func (m *Model) moveTo(i int) {
+	if i < 0 || i >= len(m.nodes) {
+		return
+	}
+	m.cursor = i
+	m.selected = m.nodes[i].tx.Txid
+	m.scroll()
+}
+
+// selectTx moves the cursor to a visible tx
+func (m *Model) selectTx(t *tx.Tx) {
+	m.moveTo(slices.IndexFunc(m.nodes, func(n *node) bool { return n.tx == t }))
+}
+
+// moveToSibling moves the cursor to the previous (-1) or next (1) child of the same parent
+func (m *Model) moveToSibling(n *node, direction int) {
+	if n.parent == nil {
+		return
+	}
+	siblings := n.parent.tx.DirectChildren()
+	i := slices.Index(siblings, n.tx) + direction
+	if i >= 0 && i < len(siblings) {
+		m.selectTx(siblings[i])
+	}
+}
+
+// scroll keeps the cursor inside the visible rows
+func (m *Model) scroll() {
+	rows := m.visibleRows()
+	if m.cursor < m.offset {
+		m.offset = m.cursor
+	} else if m.cursor >= m.offset+rows {
+		m.offset = m.cursor - rows + 1
+	}
+	m.offset = max(min(m.offset, len(m.nodes)-rows), 0)
+}
+
+// visibleRows returns the number of nodes that fit between the header and the footer
+func (m Model) visibleRows() int {
+	return max(m.height-2-2-lipgloss.Height(m.help.View(keys)), 1)
+}
+
+// renderNode renders the guides, the expand marker and a one line summary of the tx
+func (m Model) renderNode(n *node) string {
+	t := n.tx
+
+	marker := "  "
+	if len(t.Children) > 0 {
+		marker = "▾ "
+		if m.collapsed[t.Txid] {
+			marker = "▸ "
+		}
+	}
+
+	parts := []string{
+		styles.TxidStyle.Render(t.Txid),
+		styles.RecordTypeStyle.Render(t.RecordType),
+		styles.ReasonStyle.Render(t.Reason),
+	}
+
+	switch {
+	case !t.Received():
+		parts = append(parts, styles.PagerStyle.Render("(not received)"))
+	case t.RecordType == "sess":
+		parts = append(parts, styles.HostStyle.Render(t.Client.Addr()))
+	default:
+		parts = append(parts,
+			styles.ReasonStyle.Render(fmt.Sprintf("%d", t.StatusCode)),
+			styles.MethodStyle.Render(t.Method),
+			styles.UrlStyle.Render(util.TruncateString(t.Host+t.Url, 80)),
+			styles.TsFlowStyle.Render(t.SumOfSinceLast().String()),
+		)
+	}
+
+	if m.collapsed[t.Txid] && len(t.Children) > 0 {
+		parts = append(parts, styles.PagerStyle.Render(fmt.Sprintf("(+%d)", len(t.GetSortedChildren()))))
+	}
+
+	return styles.PagerStyle.Render(n.prefix) + marker + strings.Join(parts, " ")
+}
@@ -9,6 +9,7 @@ const (
 	QueryEditorView ModelState = iota
 	QueryLoaderView
 	LogView
+	DashboardView
 )
 
 type ChangeModelStateMsg struct {
@@ -28,3 +29,6 @@ type NewVarnishlogScriptMsg string
 
 // NewQueryEditorScriptMsg sets the script content in the query editor.
 type NewQueryEditorScriptMsg string
+
+// NewFilterExprMsg sets the expression filter of the transactions view.
+type NewFilterExprMsg string
@@ -14,6 +14,8 @@ var (
 	OrangeFGColor    = lipgloss.AdaptiveColor{Light: "#761801", Dark: "#f78e2f"}
 	GrayFGColor      = lipgloss.AdaptiveColor{Light: "#434964", Dark: "#6c7086"}
 	DarkGrayFGColor  = lipgloss.AdaptiveColor{Light: "#1a1b1e", Dark: "#b4abac"}
+	GreenFGColor     = lipgloss.AdaptiveColor{Light: "#2d7a3a", Dark: "#a6e3a1"}
+	BadgeFGColor     = lipgloss.AdaptiveColor{Light: "#ffffff", Dark: "#1e1e2e"}
 
 	LightGrayBGColor = lipgloss.AdaptiveColor{Light: "#cacbce", Dark: "#4a4b4e"}
 )
@@ -41,6 +43,7 @@ var (
 	MethodStyle     = lipgloss.NewStyle().Inline(true).Inherit(HostMethodURLColorStyle)
 	UrlStyle        = lipgloss.NewStyle().Inline(true).Inherit(HostMethodURLColorStyle)
 	TsFlowStyle     = lipgloss.NewStyle().Inline(true).Inherit(TimestampsColorStyle)
+	DetailsStyle    = lipgloss.NewStyle().Inline(true).Inherit(TimestampsColorStyle).Italic(true)
 
 	QueryEditorMarginStyle = lipgloss.NewStyle().Margin(1, 3)
 	QueryEditorScriptStyle = lipgloss.NewStyle().Border(lipgloss.NormalBorder(), false, false, false, true).
@@ -53,4 +56,16 @@ var (
 				Padding(0, 0, 0, 1)
 	DimmedItemStyle  = lipgloss.NewStyle().Padding(0, 0, 0, 2)
 	MatchedItemStyle = lipgloss.NewStyle().Background(LightGrayBGColor)
+
+	BadgeStyle       = lipgloss.NewStyle().Bold(true).Foreground(BadgeFGColor)
+	HitBadgeStyle    = BadgeStyle.Background(GreenFGColor)
+	MissBadgeStyle   = BadgeStyle.Background(YellowFGColor)
+	PassBadgeStyle   = BadgeStyle.Background(BlueFGColor)
+	PipeBadgeStyle   = BadgeStyle.Background(GrayFGColor)
+	SynthBadgeStyle  = BadgeStyle.Background(PaleRedFGColor)
+	HitForBadgeStyle = BadgeStyle.Background(BrownFGColor)
+	ErrorBadgeStyle  = BadgeStyle.Background(BrightRedFGColor)
+	LogBadgeStyle    = BadgeStyle.Background(DarkGrayFGColor)
+
+	IncompleteBadgeStyle = BadgeStyle.Background(OrangeFGColor)
 )
@@ -1,6 +1,8 @@
 package ui
 
 import (
+	"github.com/aorith/varnishlog-tui/internal/tx"
+	"github.com/aorith/varnishlog-tui/internal/ui/components/dashboard"
 	"github.com/aorith/varnishlog-tui/internal/ui/components/logview"
 	"github.com/aorith/varnishlog-tui/internal/ui/components/queryeditor"
 	"github.com/aorith/varnishlog-tui/internal/ui/components/queryloader"
@@ -15,11 +17,12 @@ type model struct {
 	queryEditorView queryeditor.Model
 	queryLoaderView queryloader.Model
 	logView         logview.Model
+	dashboardView   dashboard.Model
 }
 
-func StartUI(configQueries *queryloader.QueriesConfig) {
+func StartUI(configQueries *queryloader.QueriesConfig, vslFile string, retention tx.Retention) {
 	p := tea.NewProgram(
-		NewModel(configQueries),
+		NewModel(configQueries, vslFile, retention),
 		tea.WithAltScreen(),
 	)
 
@@ -29,18 +32,30 @@ func StartUI(configQueries *queryloader.QueriesConfig) {
 	}
 }
 
-func NewModel(configQueries *queryloader.QueriesConfig) model {
-	return model{
+func NewModel(configQueries *queryloader.QueriesConfig, vslFile string, retention tx.Retention) model {
+	m := model{
 		quitting:        false,
 		state:           state.QueryEditorView,
-		logView:         logview.New(),
+		logView:         logview.New(retention),
 		queryEditorView: queryeditor.New(),
 		queryLoaderView: queryloader.New(configQueries),
+		dashboardView:   dashboard.New(),
 	}
+
+	// Start directly on the transactions of the file
+	if vslFile != "" {
+		m.state = state.LogView
+		m.logView.SetVSLFile(vslFile)
+	}
+	return m
 }
 
 func (m model) Init() tea.Cmd {
-	return tea.Sequence(m.queryEditorView.Init(), m.queryLoaderView.Init(), m.logView.Init())
+	cmds := []tea.Cmd{m.queryEditorView.Init(), m.queryLoaderView.Init(), m.logView.Init(), m.dashboardView.Init()}
+	if m.state == state.LogView {
+		cmds = append(cmds, m.logView.FetchTxsCmd())
+	}
+	return tea.Sequence(cmds...)
 }
 
 func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
@@ -68,6 +83,9 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		case state.LogView:
 			m.logView, cmd = m.logView.Update(msg)
 			return m, cmd
+		case state.DashboardView:
+			m.dashboardView, cmd = m.dashboardView.Update(msg)
+			return m, cmd
 		}
 	case tea.WindowSizeMsg:
 		// Update dimensions on all models
@@ -77,6 +95,8 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		cmds = append(cmds, cmd)
 		m.queryLoaderView, cmd = m.queryLoaderView.Update(msg)
 		cmds = append(cmds, cmd)
+		m.dashboardView, cmd = m.dashboardView.Update(msg)
+		cmds = append(cmds, cmd)
 		return m, tea.Batch(cmds...)
 	case state.ChangeModelStateMsg:
 		if m.state == state.QueryEditorView && msg.State == state.LogView {
@@ -91,9 +111,12 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		switch data := msg.Data.(type) {
 		case state.NewQueryEditorScriptMsg:
 			m.queryEditorView.SetScript(string(data))
+		case state.NewFilterExprMsg:
+			cmds = append(cmds, m.logView.SetFilterExpr(string(data)))
 		}
 
 		m.state = msg.State
+		cmds = append(cmds, m.dashboardView.SetVisible(m.state == state.DashboardView))
 	default:
 		// Everything else can go through the model update even if it's not the active one
 		m.logView, cmd = m.logView.Update(msg)
@@ -102,6 +125,8 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		cmds = append(cmds, cmd)
 		m.queryLoaderView, cmd = m.queryLoaderView.Update(msg)
 		cmds = append(cmds, cmd)
+		m.dashboardView, cmd = m.dashboardView.Update(msg)
+		cmds = append(cmds, cmd)
 	}
 
 	return m, tea.Batch(cmds...)
@@ -119,6 +144,8 @@ func (m model) View() string {
 		return m.queryEditorView.View()
 	case state.QueryLoaderView:
 		return m.queryLoaderView.View()
+	case state.DashboardView:
+		return m.dashboardView.View()
 	default:
 		return "..."
 	}
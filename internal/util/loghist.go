@@ -0,0 +1,159 @@
+package util
+
+import (
+	"fmt"
+	"math"
+	"strings"
+)
+
+// logHistogramResolution is the number of buckets per decade, they are merged into the columns when drawn
+const logHistogramResolution = 60
+
+// LogHistogram counts values of several series in logarithmic buckets and draws them
+// as vertical stacked bars, like varnishhist does
+//
+//	h := NewLogHistogram(3, 11, func(v int64) ValueProvider { return DurationValue(v) }, "hit", "miss")
+//	h.Add(0, DurationValue(120*time.Microsecond))
+//	fmt.Print(h.Render(80, 20, []rune{'|', '#'}))
+type LogHistogram struct {
+	minExp, maxExp int // Decades of the first and last buckets, values outside are counted at the edges
+	newValue       func(v int64) ValueProvider
+	names          []string
+	counts         [][]int64 // Counts of each bucket of each series
+	totals         []int64
+}
+
+// NewLogHistogram returns a histogram from 10^minExp to 10^maxExp, newValue formats the axis labels
+func NewLogHistogram(minExp, maxExp int, newValue func(v int64) ValueProvider, names ...string) *LogHistogram {
+	h := &LogHistogram{
+		minExp:   minExp,
+		maxExp:   maxExp,
+		newValue: newValue,
+		names:    names,
+		counts:   make([][]int64, len(names)),
+		totals:   make([]int64, len(names)),
+	}
+	for i := range h.counts {
+		h.counts[i] = make([]int64, (maxExp-minExp)*logHistogramResolution)
+	}
+	return h
+}
+
+// Add counts the value in a series
+func (h *LogHistogram) Add(series int, v ValueProvider) {
+	buckets := h.counts[series]
+
+	var i int
+	if value := v.Value(); value > 0 {
+		i = int(math.Floor((math.Log10(float64(value)) - float64(h.minExp)) * logHistogramResolution))
+	}
+	buckets[min(max(i, 0), len(buckets)-1)]++
+	h.totals[series]++
+}
+
+// Total returns the number of values of a series
+func (h *LogHistogram) Total(series int) int64 {
+	return h.totals[series]
+}
+
+// Render draws the histogram in width columns, including the axis, and height rows of bars
+// plus the axis labels and the legend, the series are stacked from the bottom with their glyphs
+//
+//	 120 ┤       #
+//	     │      |#
+//	     │     |||##
+//	     └──────────────
+//	      1µs   10µs   100µs
+//	| hit 1200  # miss 300
+func (h *LogHistogram) Render(width, height int, glyphs []rune) string {
+	var grandTotal int64
+	for _, t := range h.totals {
+		grandTotal += t
+	}
+	labelWidth := len(fmt.Sprintf("%d", grandTotal))
+	columns := max(width-labelWidth-2, 1)
+	height = max(height, 1)
+
+	// Buckets of each column for each series
+	buckets := len(h.counts[0])
+	colCounts := make([][]int64, len(h.counts))
+	colTotals := make([]int64, columns)
+	var maxTotal int64
+	for s := range h.counts {
+		colCounts[s] = make([]int64, columns)
+		for c := 0; c < columns; c++ {
+			from := c * buckets / columns
+			to := max((c+1)*buckets/columns, from+1)
+			for b := from; b < to && b < buckets; b++ {
+				colCounts[s][c] += h.counts[s][b]
+			}
+			colTotals[c] += colCounts[s][c]
+		}
+	}
+	for _, t := range colTotals {
+		maxTotal = max(maxTotal, t)
+	}
+
+	// Rows of each column for each series, the stack is rounded so its total height is kept
+	colRows := make([][]int, columns)
+	for c := 0; c < columns; c++ {
+		colRows[c] = make([]int, len(h.counts))
+		if colTotals[c] == 0 {
+			continue
+		}
+		var stacked int64
+		prevRows := 0
+		for s := range h.counts {
+			stacked += colCounts[s][c]
+			rows := int(math.Ceil(float64(stacked) * float64(height) / float64(maxTotal)))
+			colRows[c][s] = rows - prevRows
+			prevRows = rows
+		}
+	}
+
+	var sb strings.Builder
+	for r := height - 1; r >= 0; r-- {
+		if r == height-1 {
+			sb.WriteString(fmt.Sprintf("%*d ┤", labelWidth, maxTotal))
+		} else {
+			sb.WriteString(fmt.Sprintf("%*s │", labelWidth, ""))
+		}
+		var line strings.Builder
+		for c := 0; c < columns; c++ {
+			glyph, bottom := ' ', 0
+			for s, rows := range colRows[c] {
+				if r < bottom+rows {
+					glyph = glyphs[s%len(glyphs)]
+					break
+				}
+				bottom += rows
+			}
+			line.WriteRune(glyph)
+		}
+		sb.WriteString(strings.TrimRight(line.String(), " ") + "\n")
+	}
+	sb.WriteString(fmt.Sprintf("%*s └%s\n", labelWidth, "", strings.Repeat("─", columns)))
+
+	// Labels of the decades that fit without overlapping
+	axis := []rune(strings.Repeat(" ", columns+1))
+	next := 0
+	decades := h.maxExp - h.minExp
+	for e := 0; e <= decades; e++ {
+		label := []rune(h.newValue(int64(math.Pow10(h.minExp + e))).String())
+		pos := min(e*columns/decades, columns+1-len(label))
+		if pos < next || pos < 0 {
+			continue
+		}
+		copy(axis[pos:], label)
+		next = pos + len(label) + 1
+	}
+	sb.WriteString(fmt.Sprintf("%*s  %s\n", labelWidth, "", strings.TrimRight(string(axis), " ")))
+
+	legend := make([]string, len(h.names))
+	for s, name := range h.names {
+		legend[s] = fmt.Sprintf("%c %s %d", glyphs[s%len(glyphs)], name, h.totals[s])
+	}
+	sb.WriteString(strings.Join(legend, "  ") + "\n")
+
+	return sb.String()
+}
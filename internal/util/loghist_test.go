@@ -0,0 +1,55 @@
+package util
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestLogHistogram tests the buckets, the stacked bars and the axis of the histogram.
+func TestLogHistogram(t *testing.T) {
+	h := NewLogHistogram(3, 6, func(v int64) ValueProvider { return DurationValue(v) }, "hit", "miss")
+
+	// 10 hits at 2µs, 5 misses at 500µs and the values outside of the range
+	for i := 0; i < 10; i++ {
+		h.Add(0, DurationValue(2*time.Microsecond))
+	}
+	for i := 0; i < 5; i++ {
+		h.Add(1, DurationValue(500*time.Microsecond))
+	}
+	h.Add(1, DurationValue(0))
+	h.Add(1, DurationValue(time.Hour))
+
+	if h.Total(0) != 10 || h.Total(1) != 7 {
+		t.Errorf("totals: expected 10 and 7, got %d and %d", h.Total(0), h.Total(1))
+	}
+	if got := h.counts[1][0]; got != 1 {
+		t.Errorf("first bucket: expected 1, got %d", got)
+	}
+	if got := h.counts[1][len(h.counts[1])-1]; got != 1 {
+		t.Errorf("last bucket: expected 1, got %d", got)
+	}
+
+	lines := strings.Split(strings.TrimRight(h.Render(40, 10, []rune{'|', '#'}), "\n"), "\n")
+	// 10 rows of bars, the axis, the labels and the legend
+	if len(lines) != 13 {
+		t.Fatalf("expected 13 lines, got %d:\n%s", len(lines), strings.Join(lines, "\n"))
+	}
+	if !strings.HasPrefix(lines[0], "10 ┤") {
+		t.Errorf("expected the highest column to be labeled, got %q", lines[0])
+	}
+	if strings.Count(lines[0], "|") != 1 || strings.Contains(lines[0], "#") {
+		t.Errorf("expected only the hits to reach the top, got %q", lines[0])
+	}
+	if strings.Count(lines[9], "#") != 3 {
+		t.Errorf("expected three columns of misses at the bottom, got %q", lines[9])
+	}
+	for _, label := range []string{"1µs", "10µs", "100µs", "1ms"} {
+		if !strings.Contains(lines[11], label) {
+			t.Errorf("expected the axis to contain %s, got %q", label, lines[11])
+		}
+	}
+	if lines[12] != "| hit 10  # miss 7" {
+		t.Errorf("legend: expected %q, got %q", "| hit 10  # miss 7", lines[12])
+	}
+}
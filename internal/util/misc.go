@@ -5,7 +5,6 @@ import (
 	"fmt"
 	"strconv"
 	"strings"
-	"time"
 )
 
 // ParseVarnishlogArgs sanitizes the script arguments.
@@ -31,45 +30,56 @@ func ParseVarnishlogArgs(input string) string {
 	return strings.TrimSpace(result.String())
 }
 
-// ConvertUnixTimestamp converts a Unix timestamp string (integer or fractional) to a time.Time object
-func ConvertUnixTimestamp(timestampStr string) (time.Time, error) {
-	// Check if the timestamp contains a decimal point
-	if strings.Contains(timestampStr, ".") {
-		// Split the string into seconds and fractional parts
-		parts := strings.SplitN(timestampStr, ".", 2)
-		if len(parts) != 2 {
-			return time.Time{}, fmt.Errorf("invalid timestamp format: %s", timestampStr)
-		}
+// Sum calculates the sum of the elements in the given slice.
+func Sum(arr []int) (total int) {
+	for _, v := range arr {
+		total += v
+	}
+	return total
+}
 
-		// Convert the seconds part to an int64
-		seconds, err := strconv.ParseInt(parts[0], 10, 64)
-		if err != nil {
-			return time.Time{}, fmt.Errorf("error converting seconds part: %v", err)
+// TruncateString shortens s to at most max runes, ending it with "…" when it was truncated.
+func TruncateString(s string, max int) string {
+	if len(s) <= max {
+		return s
+	}
+	var runes, cut int
+	for i := range s {
+		if runes == max-1 {
+			cut = i
 		}
-
-		// Convert the fractional part to nanoseconds
-		fractionalPart := parts[1]
-		nanoseconds, err := strconv.ParseInt(fractionalPart+strings.Repeat("0", 9-len(fractionalPart)), 10, 64)
-		if err != nil {
-			return time.Time{}, fmt.Errorf("error converting fractional part: %v", err)
+		if runes == max {
+			return s[:cut] + "…"
 		}
-
-		return time.Unix(seconds, nanoseconds), nil
+		runes++
 	}
+	return s
+}
 
-	// Integer timestamp format
-	timestampInt, err := strconv.ParseInt(timestampStr, 10, 64)
-	if err != nil {
-		return time.Time{}, fmt.Errorf("error converting timestamp: %v", err)
+// ParseByteSize parses a size in bytes with an optional K, M or G suffix (powers of 1024): 512MB, 2G, 1048576.
+func ParseByteSize(s string) (int64, error) {
+	units := []struct {
+		suffix string
+		mult   int64
+	}{
+		{"G", 1 << 30},
+		{"M", 1 << 20},
+		{"K", 1 << 10},
 	}
 
-	return time.Unix(timestampInt, 0), nil
-}
+	num := strings.TrimSuffix(strings.TrimSuffix(strings.ToUpper(strings.TrimSpace(s)), "B"), "I")
+	mult := int64(1)
+	for _, u := range units {
+		if strings.HasSuffix(num, u.suffix) {
+			num = strings.TrimSuffix(num, u.suffix)
+			mult = u.mult
+			break
+		}
+	}
 
-// Sum calculates the sum of the elements in the given slice.
-func Sum(arr []int) (total int) {
-	for _, v := range arr {
-		total += v
+	n, err := strconv.ParseInt(strings.TrimSpace(num), 10, 64)
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("invalid size %q", s)
 	}
-	return total
+	return n * mult, nil
 }
@@ -0,0 +1,57 @@
+package util
+
+import (
+	"strings"
+	"testing"
+)
+
+// TestTruncateString tests the TruncateString function.
+func TestTruncateString(t *testing.T) {
+	tests := []struct {
+		input    string
+		max      int
+		expected string
+	}{
+		{"/short", 10, "/short"},
+		{"/exactly10", 10, "/exactly10"},
+		{"/path/to/resource", 10, "/path/to/…"},
+		{"/ñandú/ñandú", 8, "/ñandú/…"},
+		{"/ñandú", 6, "/ñandú"},
+		{strings.Repeat("a", 100000), 5, "aaaa…"},
+	}
+
+	for _, tt := range tests {
+		if got := TruncateString(tt.input, tt.max); got != tt.expected {
+			t.Errorf("TruncateString(%q, %d): expected %q, got %q", TruncateString(tt.input, 20), tt.max, tt.expected, got)
+		}
+	}
+}
+
+// TestParseByteSize tests the ParseByteSize function.
+func TestParseByteSize(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected int64
+		err      bool
+	}{
+		{"1048576", 1048576, false},
+		{"512MB", 512 << 20, false},
+		{"2G", 2 << 30, false},
+		{"64KiB", 64 << 10, false},
+		{" 1 gb ", 1 << 30, false},
+		{"MB", 0, true},
+		{"-1K", 0, true},
+		{"1T", 0, true},
+	}
+
+	for _, tt := range tests {
+		got, err := ParseByteSize(tt.input)
+		if (err != nil) != tt.err {
+			t.Errorf("ParseByteSize(%q): expected error %t, got %v", tt.input, tt.err, err)
+			continue
+		}
+		if got != tt.expected {
+			t.Errorf("ParseByteSize(%q): expected %d, got %d", tt.input, tt.expected, got)
+		}
+	}
+}
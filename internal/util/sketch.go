@@ -0,0 +1,96 @@
+package util
+
+import (
+	"math"
+	"slices"
+)
+
+// Sketch estimates the quantiles of a stream of positive values with a bounded relative error.
+// Values are counted in logarithmic buckets, so its size depends on the range of the
+// values and not on how many were added: 1ns to 1h takes less than 1500 buckets with a 1% error.
+//
+//	s := NewSketch(0.01)
+//	for _, d := range durations {
+//		s.Add(float64(d))
+//	}
+//	p99 := time.Duration(s.Quantile(0.99))
+type Sketch struct {
+	gamma    float64
+	logGamma float64
+	buckets  map[int]uint64
+	zeros    uint64 // Values too small to have a bucket
+	count    uint64
+	min, max float64
+}
+
+// NewSketch returns a sketch whose quantiles are within relativeAccuracy of the real values
+func NewSketch(relativeAccuracy float64) *Sketch {
+	gamma := (1 + relativeAccuracy) / (1 - relativeAccuracy)
+	return &Sketch{
+		gamma:    gamma,
+		logGamma: math.Log(gamma),
+		buckets:  make(map[int]uint64),
+	}
+}
+
+// Add counts a value, negative values are counted as zero
+func (s *Sketch) Add(v float64) {
+	if s.count == 0 || v < s.min {
+		s.min = v
+	}
+	if s.count == 0 || v > s.max {
+		s.max = v
+	}
+	s.count++
+
+	if v < 1 {
+		s.zeros++
+		return
+	}
+	s.buckets[int(math.Ceil(math.Log(v)/s.logGamma))]++
+}
+
+// Count returns the number of values added
+func (s *Sketch) Count() uint64 {
+	return s.count
+}
+
+// Max returns the largest value added
+func (s *Sketch) Max() float64 {
+	return s.max
+}
+
+// Quantile returns the estimated value at q, between 0 and 1
+func (s *Sketch) Quantile(q float64) float64 {
+	if s.count == 0 {
+		return 0
+	}
+	if q <= 0 {
+		return s.min
+	}
+	if q >= 1 {
+		return s.max
+	}
+
+	rank := uint64(q * float64(s.count-1))
+	if rank < s.zeros {
+		return max(s.min, 0)
+	}
+
+	keys := make([]int, 0, len(s.buckets))
+	for k := range s.buckets {
+		keys = append(keys, k)
+	}
+	slices.Sort(keys)
+
+	seen := s.zeros
+	for _, k := range keys {
+		seen += s.buckets[k]
+		if seen > rank {
+			// The middle of the bucket in relative terms, clamped to the values seen
+			v := 2 * math.Pow(s.gamma, float64(k)) / (s.gamma + 1)
+			return min(max(v, s.min), s.max)
+		}
+	}
+	return s.max
+}
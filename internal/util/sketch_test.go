@@ -0,0 +1,57 @@
+package util
+
+import (
+	"math"
+	"testing"
+)
+
+// TestSketchQuantile tests that the quantiles are within the relative accuracy of the exact ones.
+func TestSketchQuantile(t *testing.T) {
+	s := NewSketch(0.01)
+	// 1µs to 100ms in nanoseconds
+	for i := 1; i <= 100000; i++ {
+		s.Add(float64(i * 1000))
+	}
+
+	tests := []struct {
+		q        float64
+		expected float64
+	}{
+		{0.5, 50000 * 1000},
+		{0.9, 90000 * 1000},
+		{0.99, 99000 * 1000},
+		{1, 100000 * 1000},
+	}
+
+	for _, tt := range tests {
+		got := s.Quantile(tt.q)
+		if math.Abs(got-tt.expected)/tt.expected > 0.01 {
+			t.Errorf("Quantile(%v): expected %v within 1%%, got %v", tt.q, tt.expected, got)
+		}
+	}
+
+	if s.Count() != 100000 {
+		t.Errorf("Count(): expected 100000, got %d", s.Count())
+	}
+	if len(s.buckets) > 1000 {
+		t.Errorf("expected less than 1000 buckets, got %d", len(s.buckets))
+	}
+}
+
+// TestSketchSmallValues tests the values without a bucket and an empty sketch.
+func TestSketchSmallValues(t *testing.T) {
+	s := NewSketch(0.01)
+	if got := s.Quantile(0.5); got != 0 {
+		t.Errorf("Quantile of an empty sketch: expected 0, got %v", got)
+	}
+
+	s.Add(0)
+	s.Add(0)
+	s.Add(10)
+	if got := s.Quantile(0.5); got != 0 {
+		t.Errorf("Quantile(0.5): expected 0, got %v", got)
+	}
+	if got := s.Max(); got != 10 {
+		t.Errorf("Max(): expected 10, got %v", got)
+	}
+}
@@ -0,0 +1,62 @@
+package util
+
+import (
+	"fmt"
+	"strings"
+)
+
+// GenerateTable generates an ASCII table based on the given headers and rows.
+// The last column is not padded so long values don't add trailing spaces.
+//
+//	headers := []string{"Name", "Value"}
+//	rows := [][]string{
+//		{"Host", "www.example.com"},
+//		{"Accept", "*/*"},
+//	}
+//
+//	Name   | Value
+//	------------------------
+//	Host   | www.example.com
+//	Accept | */*
+func GenerateTable(headers []string, rows [][]string) string {
+	var (
+		maxLens = make([]int, len(headers))
+		s       strings.Builder
+	)
+
+	for i, header := range headers {
+		maxLens[i] = len(header)
+	}
+	for _, row := range rows {
+		for j, col := range row {
+			if j < len(maxLens) && len(col) > maxLens[j] {
+				maxLens[j] = len(col)
+			}
+		}
+	}
+
+	separator := strings.Repeat("-", Sum(maxLens)+((len(headers)-1)*3)) + "\n"
+
+	writeRow := func(row []string) {
+		for j, col := range row {
+			if j >= len(maxLens) {
+				break
+			}
+			if j == len(maxLens)-1 {
+				s.WriteString(col)
+			} else {
+				s.WriteString(fmt.Sprintf("%-*s | ", maxLens[j], col))
+			}
+		}
+		s.WriteRune('\n')
+	}
+
+	s.WriteRune('\n')
+	writeRow(headers)
+	s.WriteString(separator)
+	for _, row := range rows {
+		writeRow(row)
+	}
+
+	return s.String()
+}
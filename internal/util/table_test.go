@@ -0,0 +1,28 @@
+package util
+
+import (
+	"testing"
+)
+
+// TestGenerateTable tests the GenerateTable function.
+func TestGenerateTable(t *testing.T) {
+	headers := []string{"Name", "Value"}
+	rows := [][]string{
+		{"Host", "www.example.com"},
+		{"Accept-Encoding", "gzip"},
+		{"Cookie", ""},
+	}
+
+	expectedOutput := `
+Name            | Value
+---------------------------------
+Host            | www.example.com
+Accept-Encoding | gzip
+Cookie          | 
+`
+
+	table := GenerateTable(headers, rows)
+	if table != expectedOutput {
+		t.Errorf("Expected output:\n%s\n\nGot:\n%s", expectedOutput, table)
+	}
+}
@@ -0,0 +1,124 @@
+package vsl
+
+import (
+	"fmt"
+	"net"
+	"strings"
+)
+
+// BackendConnection holds the backend connection details of a bereq
+type BackendConnection struct {
+	Name        string   // Backend name: boot.default
+	RemoteAddr  string   // Backend address: 192.168.50.11:80
+	LocalAddr   string   // Local address used for the connection
+	Reused      bool     // True if the connection was taken from the pool
+	CloseReason string   // recycle, close, ... empty if not logged
+	FetchBody   string   // Body fetch mode: length stream, chunked stream, none, ...
+	FetchErrors []string // FetchError messages
+}
+
+// updateBackend parses the backend records of a bereq
+func (t *Tx) updateBackend(parts []string, value string) {
+	if len(parts) < 2 {
+		return
+	}
+
+	switch parts[1] {
+	// -   BackendOpen    26 boot.default 192.168.50.11 80 192.168.50.10 45872 connect
+	// -   BackendOpen    31 boot.default 127.0.0.1 8081 127.0.0.1 57086
+	case "BackendOpen":
+		if len(parts) < 8 {
+			return
+		}
+		t.Backend.Name = parts[3]
+		t.Backend.RemoteAddr = net.JoinHostPort(parts[4], parts[5])
+		t.Backend.LocalAddr = net.JoinHostPort(parts[6], parts[7])
+		if len(parts) > 8 {
+			t.Backend.Reused = parts[8] == "reuse"
+		}
+
+	// -   BackendStart   127.0.0.1 8081
+	case "BackendStart":
+		if len(parts) >= 4 && t.Backend.RemoteAddr == "" {
+			t.Backend.RemoteAddr = net.JoinHostPort(parts[2], parts[3])
+		}
+
+	// -   BackendReuse   31 boot.default
+	case "BackendReuse":
+		if len(parts) >= 4 && t.Backend.Name == "" {
+			t.Backend.Name = parts[3]
+		}
+		t.Backend.CloseReason = "recycle"
+
+	// -   BackendClose   26 boot.default recycle
+	case "BackendClose":
+		if len(parts) >= 4 && t.Backend.Name == "" {
+			t.Backend.Name = parts[3]
+		}
+		if len(parts) >= 5 {
+			t.Backend.CloseReason = strings.Join(parts[4:], " ")
+		} else if t.Backend.CloseReason == "" {
+			t.Backend.CloseReason = "close"
+		}
+
+	// -   FetchError     backend boot.default: fail (errno 111, Connection refused)
+	case "FetchError":
+		t.Backend.FetchErrors = append(t.Backend.FetchErrors, value)
+
+	// -   Fetch_Body     3 length stream
+	case "Fetch_Body":
+		if len(parts) >= 4 {
+			body := parts[3]
+			if len(parts) >= 5 && parts[4] != "-" {
+				body += " " + parts[4]
+			}
+			t.Backend.FetchBody = body
+		}
+	}
+}
+
+// ConnectionDescription returns how the connection was obtained and released
+//
+//	reused, closed: recycle
+func (b BackendConnection) ConnectionDescription() string {
+	conn := "new"
+	if b.Reused {
+		conn = "reused"
+	}
+	if b.CloseReason != "" {
+		conn += ", closed: " + b.CloseReason
+	}
+	return conn
+}
+
+// BackendSummary returns a single line describing the backend connection of a bereq
+//
+//	backend boot.default 192.168.50.11:80 (new, closed: recycle) body: length stream
+func (t Tx) BackendSummary() string {
+	if t.RecordType != "bereq" {
+		return ""
+	}
+
+	b := t.Backend
+	if b.Name == "" && b.RemoteAddr == "" && len(b.FetchErrors) == 0 {
+		return "backend: -"
+	}
+
+	var s strings.Builder
+	s.WriteString("backend")
+	if b.Name != "" {
+		s.WriteString(" " + b.Name)
+	}
+	if b.RemoteAddr != "" {
+		s.WriteString(" " + b.RemoteAddr)
+	}
+	s.WriteString(fmt.Sprintf(" (%s)", b.ConnectionDescription()))
+	if b.FetchBody != "" {
+		s.WriteString(" body: " + b.FetchBody)
+	}
+	if len(b.FetchErrors) > 0 {
+		s.WriteString(fmt.Sprintf(" fetch error: %s", b.FetchErrors[len(b.FetchErrors)-1]))
+	}
+
+	return s.String()
+}
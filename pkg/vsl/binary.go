@@ -0,0 +1,315 @@
+package vsl
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// fileID is the header of the files written by varnishlog -w
+var fileID = []byte("VSL\x00")
+
+// ErrNotVSLFile is returned when the input does not start with the binary VSL file header
+var ErrNotVSLFile = errors.New("not a binary VSL file")
+
+// Layout of the first word of a record: tag, version and length of the data
+const (
+	recordLenMask  = 0xffff
+	recordVerShift = 16
+	recordVerMask  = 3
+	recordTagShift = 24
+	clientMarker   = 1 << 30
+	backendMarker  = 1 << 31
+	identMask      = ^uint32(clientMarker | backendMarker)
+	recordVersion3 = 1   // 64 bit vxids, 3 words of overhead instead of 2
+	batchTag       = 255 // SLT__Batch, the records of the batch follow it
+)
+
+// Tags maps the tag numbers of the binary format to their names.
+//
+// The numbers are not part of the file, they come from the tag table of the
+// varnishd that wrote it. This table follows Varnish 6.0 and is a best effort:
+// tags added by later versions are appended at the end so they usually keep
+// their numbers, but a file from a varnishd with a different table can show
+// wrong tag names. Unknown numbers are shown as Tag<N>. The names are also
+// the tags accepted by the VSL queries.
+var Tags = []string{
+	0:  "Bogus",
+	1:  "Debug",
+	2:  "Error",
+	3:  "CLI",
+	4:  "SessOpen",
+	5:  "SessClose",
+	6:  "BackendOpen",
+	7:  "BackendReuse",
+	8:  "BackendClose",
+	9:  "HttpGarbage",
+	10: "Proxy",
+	11: "ProxyGarbage",
+	12: "Backend",
+	13: "Length",
+	14: "FetchError",
+	15: "ReqMethod",
+	16: "ReqURL",
+	17: "ReqProtocol",
+	18: "ReqStatus",
+	19: "ReqReason",
+	20: "ReqHeader",
+	21: "ReqUnset",
+	22: "ReqLost",
+	23: "RespMethod",
+	24: "RespURL",
+	25: "RespProtocol",
+	26: "RespStatus",
+	27: "RespReason",
+	28: "RespHeader",
+	29: "RespUnset",
+	30: "RespLost",
+	31: "BereqMethod",
+	32: "BereqURL",
+	33: "BereqProtocol",
+	34: "BereqStatus",
+	35: "BereqReason",
+	36: "BereqHeader",
+	37: "BereqUnset",
+	38: "BereqLost",
+	39: "BerespMethod",
+	40: "BerespURL",
+	41: "BerespProtocol",
+	42: "BerespStatus",
+	43: "BerespReason",
+	44: "BerespHeader",
+	45: "BerespUnset",
+	46: "BerespLost",
+	47: "ObjMethod",
+	48: "ObjURL",
+	49: "ObjProtocol",
+	50: "ObjStatus",
+	51: "ObjReason",
+	52: "ObjHeader",
+	53: "ObjUnset",
+	54: "ObjLost",
+	55: "BogoHeader",
+	56: "LostHeader",
+	57: "TTL",
+	58: "Fetch_Body",
+	59: "VCL_acl",
+	60: "VCL_call",
+	61: "VCL_trace",
+	62: "VCL_return",
+	63: "ReqStart",
+	64: "Hit",
+	65: "HitPass",
+	66: "ExpBan",
+	67: "ExpKill",
+	68: "WorkThread",
+	69: "ESI_xmlerror",
+	70: "Hash",
+	71: "Backend_health",
+	72: "VCL_Log",
+	73: "VCL_Error",
+	74: "Gzip",
+	75: "Link",
+	76: "Begin",
+	77: "End",
+	78: "VSL",
+	79: "Storage",
+	80: "Timestamp",
+	81: "ReqAcct",
+	82: "PipeAcct",
+	83: "BereqAcct",
+	84: "VfpAcct",
+	85: "Witness",
+	86: "BackendStart",
+	87: "H2RxHdr",
+	88: "H2RxBody",
+	89: "H2TxHdr",
+	90: "H2TxBody",
+	91: "HitMiss",
+	92: "Filters",
+	93: "SessError",
+	94: "VCL_use",
+	95: "Notice",
+	96: "VdpAcct",
+}
+
+// Record is a single record of a binary VSL file or of the raw log of a tx
+type Record struct {
+	Vxid  uint64
+	Tag   string
+	Kind  byte // 'c' client, 'b' backend or '-'
+	Value string
+}
+
+// String formats the record as a line of varnishlog -g raw
+//
+//	32770 ReqMethod      c GET
+func (r Record) String() string {
+	return strings.TrimRight(fmt.Sprintf("%10d %-14s %c %s", r.Vxid, r.Tag, r.Kind, r.Value), " ")
+}
+
+// RecordReader reads the records of a binary VSL file written by varnishlog -w.
+// The words of the records are read as little endian, the byte order of the
+// machines running varnishd in practice.
+type RecordReader struct {
+	r      *bufio.Reader
+	record Record
+	err    error
+}
+
+// NewRecordReader returns a RecordReader reading from r, it fails with
+// ErrNotVSLFile if r does not start with the header of a binary VSL file
+func NewRecordReader(r io.Reader) (*RecordReader, error) {
+	br := bufio.NewReader(r)
+	header := make([]byte, len(fileID))
+	if _, err := io.ReadFull(br, header); err != nil || !bytes.Equal(header, fileID) {
+		return nil, ErrNotVSLFile
+	}
+	return &RecordReader{r: br}, nil
+}
+
+// Next advances to the next record, which will then be available through Record.
+// It returns false at the end of the input or on error, available through Err.
+func (rr *RecordReader) Next() bool {
+	if rr.err != nil {
+		return false
+	}
+
+	for {
+		word0, err := rr.readWord()
+		if err != nil {
+			if err != io.EOF {
+				rr.err = err
+			}
+			return false
+		}
+
+		tag := word0 >> recordTagShift
+		version3 := (word0>>recordVerShift)&recordVerMask == recordVersion3
+		if tag == batchTag {
+			// Only the batch length follows in both versions, the records are read one by one.
+			// The id of a batch is the one of its first record
+			if _, err := rr.readWord(); err != nil {
+				rr.err = truncated(err)
+				return false
+			}
+			continue
+		}
+
+		var vxid uint64
+		var markers uint32
+		word1, err := rr.readWord()
+		if err != nil {
+			rr.err = truncated(err)
+			return false
+		}
+		if version3 {
+			word2, err := rr.readWord()
+			if err != nil {
+				rr.err = truncated(err)
+				return false
+			}
+			vxid = uint64(word2&identMask)<<32 | uint64(word1)
+			markers = word2
+		} else {
+			vxid = uint64(word1 & identMask)
+			markers = word1
+		}
+
+		length := int(word0 & recordLenMask)
+		data := make([]byte, (length+3)/4*4)
+		if _, err := io.ReadFull(rr.r, data); err != nil {
+			rr.err = truncated(err)
+			return false
+		}
+
+		rr.record = Record{
+			Vxid:  vxid,
+			Tag:   tagName(tag),
+			Kind:  recordKind(markers),
+			Value: string(bytes.TrimRight(data[:length], "\x00")),
+		}
+		return true
+	}
+}
+
+// Record returns the record read by the last call to Next
+func (rr *RecordReader) Record() Record {
+	return rr.record
+}
+
+// Err returns the error that stopped the reader, reaching the end of the input is not an error
+func (rr *RecordReader) Err() error {
+	return rr.err
+}
+
+func (rr *RecordReader) readWord() (uint32, error) {
+	var word [4]byte
+	if _, err := io.ReadFull(rr.r, word[:]); err != nil {
+		return 0, err
+	}
+	return binary.LittleEndian.Uint32(word[:]), nil
+}
+
+func truncated(err error) error {
+	if err == io.EOF || err == io.ErrUnexpectedEOF {
+		return fmt.Errorf("truncated record: %w", io.ErrUnexpectedEOF)
+	}
+	return err
+}
+
+func tagName(tag uint32) string {
+	if int(tag) < len(Tags) && Tags[tag] != "" {
+		return Tags[tag]
+	}
+	return fmt.Sprintf("Tag%d", tag)
+}
+
+func recordKind(markers uint32) byte {
+	switch {
+	case markers&clientMarker != 0:
+		return 'c'
+	case markers&backendMarker != 0:
+		return 'b'
+	}
+	return '-'
+}
+
+// rawTextReader formats the records of a RecordReader as the output of varnishlog -g raw
+type rawTextReader struct {
+	records *RecordReader
+	buf     []byte
+}
+
+func (t *rawTextReader) Read(p []byte) (int, error) {
+	for len(t.buf) == 0 {
+		if !t.records.Next() {
+			if err := t.records.Err(); err != nil {
+				return 0, err
+			}
+			return 0, io.EOF
+		}
+		t.buf = append(t.buf, t.records.Record().String()...)
+		t.buf = append(t.buf, '\n')
+	}
+	n := copy(p, t.buf)
+	t.buf = t.buf[n:]
+	return n, nil
+}
+
+// NewBinaryParser returns a Parser reading a binary VSL file written by varnishlog -w,
+// the records are grouped by vxid as with -g raw
+//
+//	f, _ := os.Open("varnish.vsl")
+//	p, err := vsl.NewBinaryParser(f)
+func NewBinaryParser(r io.Reader) (*Parser, error) {
+	records, err := NewRecordReader(r)
+	if err != nil {
+		return nil, err
+	}
+	return NewParser(&rawTextReader{records: records}), nil
+}
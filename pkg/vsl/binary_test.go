@@ -0,0 +1,127 @@
+package vsl
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// writeTestRecord appends a record to a binary VSL file
+func writeTestRecord(buf *bytes.Buffer, version3 bool, tag string, vxid uint64, markers uint32, value string) {
+	data := append([]byte(value), 0)
+	word0 := uint32(slices.Index(Tags, tag))<<recordTagShift | uint32(len(data))
+	if version3 {
+		word0 |= recordVersion3 << recordVerShift
+		binary.Write(buf, binary.LittleEndian, []uint32{word0, uint32(vxid), uint32(vxid>>32) | markers})
+	} else {
+		binary.Write(buf, binary.LittleEndian, []uint32{word0, uint32(vxid) | markers})
+	}
+	buf.Write(data)
+	buf.Write(make([]byte, (len(data)+3)/4*4-len(data)))
+}
+
+// writeTestBatch appends the records of a batch as VSL_Flush writes them: the batch
+// tag, the length in bytes of the records and the records right after
+func writeTestBatch(buf *bytes.Buffer, records []byte) {
+	binary.Write(buf, binary.LittleEndian, []uint32{uint32(batchTag) << recordTagShift, uint32(len(records))})
+	buf.Write(records)
+}
+
+func testVSLFile(version3 bool) *bytes.Buffer {
+	buf := bytes.NewBuffer(slices.Clone(fileID))
+	writeTestRecord(buf, version3, "CLI", 0, 0, "Rd ping")
+	batch := new(bytes.Buffer)
+	writeTestRecord(batch, version3, "Begin", 32770, clientMarker, "req 32769 rxreq")
+	writeTestRecord(batch, version3, "ReqMethod", 32770, clientMarker, "GET")
+	writeTestBatch(buf, batch.Bytes())
+	writeTestRecord(buf, version3, "Begin", 32771, backendMarker, "bereq 32770 fetch")
+	writeTestRecord(buf, version3, "ReqURL", 32770, clientMarker, "/index.html")
+	writeTestRecord(buf, version3, "Link", 32770, clientMarker, "bereq 32771 fetch")
+	writeTestRecord(buf, version3, "BerespStatus", 32771, backendMarker, "200")
+	writeTestRecord(buf, version3, "End", 32771, backendMarker, "")
+	writeTestRecord(buf, version3, "RespStatus", 32770, clientMarker, "200")
+	writeTestRecord(buf, version3, "End", 32770, clientMarker, "")
+	return buf
+}
+
+// TestRecordReader tests the decoding of the records of both versions of the format
+func TestRecordReader(t *testing.T) {
+	for _, version3 := range []bool{false, true} {
+		rr, err := NewRecordReader(testVSLFile(version3))
+		if err != nil {
+			t.Fatalf("Unexpected error: %s", err)
+		}
+
+		var lines []string
+		for rr.Next() {
+			lines = append(lines, strings.TrimSpace(rr.Record().String()))
+		}
+		if err := rr.Err(); err != nil {
+			t.Fatalf("Unexpected error: %s", err)
+		}
+
+		expected := []string{
+			"0 CLI            - Rd ping",
+			"32770 Begin          c req 32769 rxreq",
+			"32770 ReqMethod      c GET",
+			"32771 Begin          b bereq 32770 fetch",
+			"32770 ReqURL         c /index.html",
+			"32770 Link           c bereq 32771 fetch",
+			"32771 BerespStatus   b 200",
+			"32771 End            b",
+			"32770 RespStatus     c 200",
+			"32770 End            c",
+		}
+		if !slices.Equal(lines, expected) {
+			t.Errorf("version3=%v: expected records:\n%s\ngot:\n%s", version3, strings.Join(expected, "\n"), strings.Join(lines, "\n"))
+		}
+	}
+}
+
+// TestRecordReaderErrors tests files that are not VSL files or are truncated
+func TestRecordReaderErrors(t *testing.T) {
+	if _, err := NewRecordReader(strings.NewReader("*   << Request  >> 5\n")); !errors.Is(err, ErrNotVSLFile) {
+		t.Errorf("Expected ErrNotVSLFile, got %v", err)
+	}
+
+	buf := testVSLFile(false)
+	rr, err := NewRecordReader(bytes.NewReader(buf.Bytes()[:buf.Len()-2]))
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	for rr.Next() {
+	}
+	if rr.Err() == nil {
+		t.Errorf("Expected an error for a truncated file")
+	}
+}
+
+// TestBinaryParser tests that the records of a binary file end up in the same txs as the text output
+func TestBinaryParser(t *testing.T) {
+	p, err := NewBinaryParser(testVSLFile(true))
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+
+	var txs []*Tx
+	for p.Next() {
+		txs = append(txs, p.Tx())
+	}
+	if err := p.Err(); err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	if len(txs) != 2 {
+		t.Fatalf("Expected 2 txs, got %d", len(txs))
+	}
+
+	bereq, req := txs[0], txs[1]
+	if bereq.Txid != "32771" || bereq.RecordType != "bereq" || bereq.StatusCode != 200 {
+		t.Errorf("Unexpected bereq: %+v", bereq)
+	}
+	if req.Txid != "32770" || req.Method != "GET" || req.Url != "/index.html" || req.StatusCode != 200 || len(req.Links) != 1 {
+		t.Errorf("Unexpected req: %+v", req)
+	}
+}
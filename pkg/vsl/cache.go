@@ -0,0 +1,94 @@
+package vsl
+
+import (
+	"strconv"
+)
+
+// CacheOutcome is the result of the cache lookup of a request
+type CacheOutcome string
+
+const (
+	OutcomeUnknown CacheOutcome = ""
+	OutcomeHit     CacheOutcome = "hit"
+	OutcomeMiss    CacheOutcome = "miss"
+	OutcomePass    CacheOutcome = "pass"
+	OutcomePipe    CacheOutcome = "pipe"
+	OutcomeSynth   CacheOutcome = "synth"
+	OutcomeHitMiss CacheOutcome = "hit-for-miss"
+	OutcomeHitPass CacheOutcome = "hit-for-pass"
+)
+
+// String returns the outcome or "-" when unknown
+func (o CacheOutcome) String() string {
+	if o == OutcomeUnknown {
+		return "-"
+	}
+	return string(o)
+}
+
+// updateCacheOutcome derives the cache outcome of a req from a single VSL line.
+// Hit records take precedence, otherwise the first VCL decision wins.
+func (t *Tx) updateCacheOutcome(parts []string) {
+	if t.RecordType != "req" || len(parts) < 3 {
+		return
+	}
+
+	switch parts[1] {
+	// -   Hit            32771 119.993 10.000 0.000
+	// -   HitMiss        32771 119.993
+	// -   HitPass        32771 119.993
+	case "Hit", "HitMiss", "HitPass":
+		switch parts[1] {
+		case "Hit":
+			t.CacheOutcome = OutcomeHit
+		case "HitMiss":
+			t.CacheOutcome = OutcomeHitMiss
+		case "HitPass":
+			t.CacheOutcome = OutcomeHitPass
+		}
+		if vxid, err := strconv.ParseUint(parts[2], 10, 64); err == nil {
+			t.HitVxid = vxid
+		}
+
+	// -   VCL_return     pass
+	case "VCL_return":
+		switch parts[2] {
+		case "pass":
+			t.setCacheOutcome(OutcomePass)
+		case "pipe":
+			t.setCacheOutcome(OutcomePipe)
+		case "synth":
+			t.setCacheOutcome(OutcomeSynth)
+		}
+
+	// -   VCL_call       MISS
+	case "VCL_call":
+		switch parts[2] {
+		case "MISS":
+			t.setCacheOutcome(OutcomeMiss)
+		case "PASS":
+			t.setCacheOutcome(OutcomePass)
+		case "PIPE":
+			t.setCacheOutcome(OutcomePipe)
+		}
+
+	// -   Link           bereq 32771 fetch
+	case "Link":
+		if len(parts) < 5 || parts[2] != "bereq" {
+			return
+		}
+		switch parts[4] {
+		case "fetch":
+			t.setCacheOutcome(OutcomeMiss)
+		case "pass":
+			t.setCacheOutcome(OutcomePass)
+		}
+	}
+}
+
+// setCacheOutcome sets the outcome only if it is still unknown
+func (t *Tx) setCacheOutcome(o CacheOutcome) {
+	if t.CacheOutcome == OutcomeUnknown {
+		t.CacheOutcome = o
+	}
+}
@@ -0,0 +1,91 @@
+package vsl
+
+import (
+	"fmt"
+	"strings"
+)
+
+// HeaderChange is a modification of the URL or a header made while a VCL subroutine was running
+type HeaderChange struct {
+	Sub      string // VCL_call that was running: RECV, DELIVER, ...
+	Family   string // Req, Resp, Bereq or Beresp
+	Action   string // "set", "unset" or "url"
+	Name     string // Header name, empty for url changes
+	Value    string // New value or the unset value
+	OldValue string // Previous value when a header or the url is replaced
+}
+
+// SubroutineChanges groups the consecutive changes made by the same VCL subroutine
+type SubroutineChanges struct {
+	Sub     string
+	Changes []HeaderChange
+}
+
+// SubName returns the VCL subroutine name of a VCL_call: RECV -> vcl_recv
+func SubName(call string) string {
+	return "vcl_" + strings.ToLower(call)
+}
+
+// String returns a short description of the change
+//
+//	set req X-Foo
+//	unset resp Cookie
+//	url req /path
+func (c HeaderChange) String() string {
+	family := strings.ToLower(c.Family)
+	if c.Action == "url" {
+		return fmt.Sprintf("url %s %s", family, c.Value)
+	}
+	return fmt.Sprintf("%s %s %s", c.Action, family, c.Name)
+}
+
+// String returns a summary of the changes made by the subroutine
+//
+//	vcl_recv set req X-Foo, unset req Cookie
+func (sc SubroutineChanges) String() string {
+	changes := make([]string, len(sc.Changes))
+	for i, c := range sc.Changes {
+		changes[i] = c.String()
+	}
+	return fmt.Sprintf("%s %s", SubName(sc.Sub), strings.Join(changes, ", "))
+}
+
+// HeaderTimeline returns the header changes of the tx grouped by VCL subroutine
+// in the order they happened
+func (t Tx) HeaderTimeline() []SubroutineChanges {
+	var timeline []SubroutineChanges
+	for _, c := range t.HeaderChanges {
+		last := len(timeline) - 1
+		if last >= 0 && timeline[last].Sub == c.Sub {
+			timeline[last].Changes = append(timeline[last].Changes, c)
+			continue
+		}
+		timeline = append(timeline, SubroutineChanges{Sub: c.Sub, Changes: []HeaderChange{c}})
+	}
+	return timeline
+}
+
+// addHeaderChange appends a change to the tx, a "set" right after an "unset" of the
+// same header in the same subroutine is merged as a replacement (set req.http.X = ...)
+func (t *Tx) addHeaderChange(c HeaderChange) {
+	last := len(t.HeaderChanges) - 1
+	if c.Action == "set" && last >= 0 {
+		prev := t.HeaderChanges[last]
+		if prev.Action == "unset" && prev.Sub == c.Sub && prev.Family == c.Family && strings.EqualFold(prev.Name, c.Name) {
+			c.OldValue = prev.Value
+			t.HeaderChanges[last] = c
+			return
+		}
+	}
+	t.HeaderChanges = append(t.HeaderChanges, c)
+}
+
+// headerTagFamily returns the family name (Req, Resp, ...) of a VSL header or URL tag
+func headerTagFamily(tag string) string {
+	for _, suffix := range []string{"Header", "Unset", "URL"} {
+		if strings.HasSuffix(tag, suffix) {
+			return strings.TrimSuffix(tag, suffix)
+		}
+	}
+	return ""
+}
@@ -0,0 +1,141 @@
+package vsl
+
+import (
+	"fmt"
+	"net"
+	"strings"
+	"time"
+)
+
+// ClientInfo holds who made a request, taken from SessOpen, ReqStart and the request headers
+type ClientInfo struct {
+	IP            string    // Client IP
+	Port          string    // Client port
+	Listener      string    // Listen socket name: a0, http, ...
+	LocalAddr     string    // Address the client connected to: 192.168.50.10:80
+	OpenTime      time.Time // Time the session was opened
+	Protocol      string    // ReqProtocol: HTTP/1.1, HTTP/2.0, ...
+	XForwardedFor []string  // X-Forwarded-For chain, the last one is the closest to varnish
+}
+
+// updateClient parses the client records of a session or request
+func (t *Tx) updateClient(parts []string) error {
+	if len(parts) < 3 {
+		return nil
+	}
+
+	switch parts[1] {
+	// -   SessOpen       192.168.50.1 50312 a0 192.168.50.10 80 1714899853.663185 23
+	case "SessOpen":
+		if len(parts) < 8 {
+			return nil
+		}
+		t.Client.IP = parts[2]
+		t.Client.Port = parts[3]
+		t.Client.Listener = parts[4]
+		t.Client.LocalAddr = net.JoinHostPort(parts[5], parts[6])
+		openTime, err := parseUnixTimestamp(parts[7])
+		if err != nil {
+			return fmt.Errorf("invalid SessOpen: unparsable time field: %w", err)
+		}
+		t.Client.OpenTime = openTime
+
+	// -   ReqStart       192.168.50.1 50312 a0
+	case "ReqStart":
+		if len(parts) < 4 {
+			return nil
+		}
+		t.Client.IP = parts[2]
+		t.Client.Port = parts[3]
+		if len(parts) > 4 {
+			t.Client.Listener = parts[4]
+		}
+
+	// Want the first value, the protocol the client used
+	// -   ReqProtocol    HTTP/1.1
+	case "ReqProtocol":
+		if t.Client.Protocol == "" {
+			t.Client.Protocol = parts[2]
+		}
+	}
+
+	return nil
+}
+
+// setForwardedFor extracts the X-Forwarded-For chain from the received request headers
+func (t *Tx) setForwardedFor() {
+	for _, value := range t.ReqHeaders.Original.Values("X-Forwarded-For") {
+		for _, ip := range strings.Split(value, ",") {
+			if ip = strings.TrimSpace(ip); ip != "" {
+				t.Client.XForwardedFor = append(t.Client.XForwardedFor, ip)
+			}
+		}
+	}
+}
+
+// InheritClient fills the client fields missing in the tx with the ones of its parent,
+// requests of a session and their bereqs / ESI subrequests share the same client
+func (t *Tx) InheritClient(parent *Tx) {
+	c := &t.Client
+	p := parent.Client
+	if c.IP == "" {
+		c.IP = p.IP
+		c.Port = p.Port
+	}
+	if c.Listener == "" {
+		c.Listener = p.Listener
+	}
+	if c.LocalAddr == "" {
+		c.LocalAddr = p.LocalAddr
+	}
+	if c.OpenTime.IsZero() {
+		c.OpenTime = p.OpenTime
+	}
+	if c.Protocol == "" {
+		c.Protocol = p.Protocol
+	}
+	if len(c.XForwardedFor) == 0 {
+		c.XForwardedFor = p.XForwardedFor
+	}
+}
+
+// Addr returns the client address: 192.168.50.1:50312
+func (c ClientInfo) Addr() string {
+	if c.IP == "" {
+		return ""
+	}
+	if c.Port == "" {
+		return c.IP
+	}
+	return net.JoinHostPort(c.IP, c.Port)
+}
+
+// ClientSummary returns a single line describing who made the request
+//
+//	client 192.168.50.1:50312 via a0 HTTP/1.1 xff: 10.0.0.1, 192.168.50.1
+func (t Tx) ClientSummary() string {
+	c := t.Client
+	if c.IP == "" {
+		return ""
+	}
+
+	var s strings.Builder
+	s.WriteString("client " + c.Addr())
+	if c.Listener != "" {
+		s.WriteString(" via " + c.Listener)
+	}
+	if t.RecordType == "sess" && c.LocalAddr != "" {
+		s.WriteString(" to " + c.LocalAddr)
+	}
+	if c.Protocol != "" {
+		s.WriteString(" " + c.Protocol)
+	}
+	if len(c.XForwardedFor) > 0 {
+		s.WriteString(" xff: " + strings.Join(c.XForwardedFor, ", "))
+	}
+	if t.RecordType == "sess" && !c.OpenTime.IsZero() {
+		s.WriteString(" opened at " + c.OpenTime.Format(time.RFC3339))
+	}
+
+	return s.String()
+}
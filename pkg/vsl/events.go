@@ -0,0 +1,34 @@
+package vsl
+
+// Event is a message logged with std.log() or by varnish itself
+type Event struct {
+	Tag     string // VCL_Log, VCL_Error, Error or Debug
+	Sub     string // VCL_call running when it was logged, empty outside of VCL
+	Message string
+}
+
+// IsError reports whether the event is an error
+func (e Event) IsError() bool {
+	return e.Tag == "VCL_Error" || e.Tag == "Error"
+}
+
+// isEventTag reports whether the VSL tag is collected as an event
+func isEventTag(tag string) bool {
+	switch tag {
+	case "VCL_Log", "VCL_Error", "Error", "Debug":
+		return true
+	}
+	return false
+}
+
+// CountEvents returns the number of error events and the number of log events
+func (t Tx) CountEvents() (errors, logs int) {
+	for _, e := range t.Events {
+		if e.IsError() {
+			errors++
+		} else {
+			logs++
+		}
+	}
+	return errors, logs
+}
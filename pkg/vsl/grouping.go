@@ -0,0 +1,228 @@
+package vsl
+
+import (
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// Grouping is the varnishlog grouping mode (-g) of the input
+type Grouping int
+
+const (
+	GroupingUnknown Grouping = iota // Not detected yet
+	GroupingVxid                    // -g vxid, one tx per group
+	GroupingRequest                 // -g request, a client request and its children
+	GroupingSession                 // -g session, a session and all its requests
+	GroupingRaw                     // -g raw, no groups and interleaved records
+)
+
+func (g Grouping) String() string {
+	switch g {
+	case GroupingVxid:
+		return "vxid"
+	case GroupingRequest:
+		return "request"
+	case GroupingSession:
+		return "session"
+	case GroupingRaw:
+		return "raw"
+	}
+	return "unknown"
+}
+
+// maxPendingRawTxs is the number of raw txs waiting for their End record
+// before the oldest one is emitted without it, -q filters in raw mode
+// apply to single records so End may never be logged
+const maxPendingRawTxs = 10000
+
+// detectGrouping returns the grouping of a line of varnishlog
+func detectGrouping(parts []string) Grouping {
+	// *   << Request  >> 5
+	if isGroupHeader(parts) {
+		return GroupingVxid
+	}
+	//          5 ReqMethod      c GET
+	if isRawRecord(parts) {
+		return GroupingRaw
+	}
+	return GroupingUnknown
+}
+
+// isGroupHeader reports whether the line starts a tx in the grouped modes
+func isGroupHeader(parts []string) bool {
+	// *   << Session  >> 16812342
+	// **  << Request  >> 4
+	// *4* << BeReq    >> 6
+	return len(parts) == 5 && parts[0][0] == '*' && parts[1] == "<<" && parts[3] == ">>"
+}
+
+// groupLevel returns the nesting level of a group header or record prefix: *, **, -3-, ...
+func groupLevel(prefix string) int {
+	if level, err := strconv.Atoi(strings.Trim(prefix, "*-")); err == nil {
+		return level
+	}
+	return len(prefix)
+}
+
+// refine narrows down a grouping detected as vxid with the next group header,
+// nested txs only exist when grouping by request or session
+func (g Grouping) refine(parts []string) Grouping {
+	if g != GroupingVxid && g != GroupingRequest {
+		return g
+	}
+	level := groupLevel(parts[0])
+	if level == 1 && parts[2] == "Session" {
+		return GroupingSession
+	}
+	if level > 1 {
+		return GroupingRequest
+	}
+	return g
+}
+
+// isRawRecord reports whether the line is a record of -g raw: vxid, tag, type and value
+//
+//	32770 Begin          c req 32769 rxreq
+func isRawRecord(parts []string) bool {
+	if len(parts) < 3 || len(parts[2]) != 1 || !strings.Contains("cb-", parts[2]) {
+		return false
+	}
+	_, err := strconv.ParseUint(parts[0], 10, 64)
+	return err == nil
+}
+
+// rawGroup holds the records of a raw tx until its End record
+type rawGroup struct {
+	vxid    uint64
+	kind    string // c (client) or b (backend)
+	begin   string // Value of the Begin record
+	end     *string
+	records []string
+}
+
+// rawAssembler groups the interleaved records of -g raw by vxid
+type rawAssembler struct {
+	groups map[uint64]*rawGroup
+	order  []*rawGroup // Pending groups oldest first, the removed ones are skipped when found
+}
+
+func newRawAssembler() *rawAssembler {
+	return &rawAssembler{groups: make(map[uint64]*rawGroup)}
+}
+
+// add adds a raw record and returns the lines of the txs it completes
+// in the layout of -g vxid, so they can be parsed by ParseTx
+func (a *rawAssembler) add(line string, parts []string) [][]string {
+	vxid, err := strconv.ParseUint(parts[0], 10, 64)
+	if err != nil || vxid == 0 {
+		// Non-transactional records such as CLI
+		return nil
+	}
+
+	g, ok := a.groups[vxid]
+	if !ok {
+		g = &rawGroup{vxid: vxid, kind: parts[2]}
+		a.groups[vxid] = g
+		a.order = append(a.order, g)
+	}
+
+	tag, value := parts[1], skipFields(line, 3)
+	switch tag {
+	case "Begin":
+		g.begin = value
+	case "End":
+		g.end = &value
+		a.remove(g)
+		return [][]string{g.lines()}
+	default:
+		g.records = append(g.records, rawRecordLine(tag, value))
+	}
+
+	var done [][]string
+	for len(a.groups) > maxPendingRawTxs {
+		oldest := a.order[0]
+		a.order = a.order[1:]
+		if a.pending(oldest) {
+			a.remove(oldest)
+			done = append(done, oldest.lines())
+		}
+	}
+	return done
+}
+
+// flush returns the lines of all the pending txs, oldest first
+func (a *rawAssembler) flush() [][]string {
+	var done [][]string
+	for _, g := range a.order {
+		if a.pending(g) {
+			done = append(done, g.lines())
+		}
+	}
+	a.groups = make(map[uint64]*rawGroup)
+	a.order = nil
+	return done
+}
+
+// pending returns true if the group was not removed, its vxid may have been reused since
+func (a *rawAssembler) pending(g *rawGroup) bool {
+	return a.groups[g.vxid] == g
+}
+
+// remove deletes the group leaving it in the order, which is compacted once it
+// grows to twice the pending limit so a group that never ends does not make it grow
+func (a *rawAssembler) remove(g *rawGroup) {
+	delete(a.groups, g.vxid)
+	if len(a.order) >= 2*maxPendingRawTxs {
+		a.order = slices.DeleteFunc(a.order, func(g *rawGroup) bool { return !a.pending(g) })
+	}
+}
+
+// lines rebuilds the tx with a group header, the Begin record is guessed
+// when it was not logged and End is left out so the tx is marked as incomplete
+func (g *rawGroup) lines() []string {
+	begin := g.begin
+	if begin == "" {
+		begin = g.guessRecordType() + " 0 unknown"
+	}
+
+	var groupType string
+	switch strings.Fields(begin)[0] {
+	case "sess":
+		groupType = "Session"
+	case "req":
+		groupType = "Request"
+	case "bereq":
+		groupType = "BeReq"
+	default:
+		groupType = "Unknown"
+	}
+
+	lines := make([]string, 0, len(g.records)+3)
+	lines = append(lines, fmt.Sprintf("*   << %-7s >> %d", groupType, g.vxid))
+	lines = append(lines, rawRecordLine("Begin", begin))
+	lines = append(lines, g.records...)
+	if g.end != nil {
+		lines = append(lines, rawRecordLine("End", *g.end))
+	}
+	return lines
+}
+
+// rawRecordLine formats a raw record as a record of -g vxid
+func rawRecordLine(tag, value string) string {
+	// -   ReqMethod      GET
+	return strings.TrimRight(fmt.Sprintf("-   %-14s %s", tag, value), " ")
+}
+
+func (g *rawGroup) guessRecordType() string {
+	if g.kind == "b" {
+		return "bereq"
+	}
+	for _, r := range g.records {
+		if strings.HasPrefix(r, "-   SessOpen ") {
+			return "sess"
+		}
+	}
+	return "req"
+}
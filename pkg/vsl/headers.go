@@ -0,0 +1,123 @@
+package vsl
+
+import (
+	"slices"
+	"strings"
+)
+
+// Header is a single header line as seen in the VSL
+type Header struct {
+	Name  string
+	Value string
+}
+
+// Headers is an ordered collection of headers, the same name can appear multiple times
+type Headers []Header
+
+// HeaderSet holds the headers of one family (req, resp, bereq, beresp or obj)
+type HeaderSet struct {
+	Original Headers // Headers before any VCL subroutine was called
+	Final    Headers // Headers after applying all the sets and unsets
+}
+
+// Get returns the first value of the header 'name' (case insensitive)
+func (h Headers) Get(name string) string {
+	for _, hdr := range h {
+		if strings.EqualFold(hdr.Name, name) {
+			return hdr.Value
+		}
+	}
+	return ""
+}
+
+// Values returns all the values of the header 'name' (case insensitive) in order
+func (h Headers) Values(name string) []string {
+	var values []string
+	for _, hdr := range h {
+		if strings.EqualFold(hdr.Name, name) {
+			values = append(values, hdr.Value)
+		}
+	}
+	return values
+}
+
+// remove deletes the first header matching name and value, if no header matches
+// exactly the first one matching only the name is removed
+func (h Headers) remove(name, value string) Headers {
+	idx := slices.IndexFunc(h, func(hdr Header) bool {
+		return strings.EqualFold(hdr.Name, name) && hdr.Value == value
+	})
+	if idx < 0 {
+		idx = slices.IndexFunc(h, func(hdr Header) bool {
+			return strings.EqualFold(hdr.Name, name)
+		})
+	}
+	if idx < 0 {
+		return h
+	}
+	return slices.Delete(h, idx, idx+1)
+}
+
+// Modified reports whether the final headers differ from the original ones
+func (hs HeaderSet) Modified() bool {
+	return !slices.Equal(hs.Original, hs.Final)
+}
+
+// parseHeader splits a VSL header value into its name and value
+//
+//	Host: www.example.com
+//	Host:www.example.com
+func parseHeader(s string) (Header, bool) {
+	name, value, found := strings.Cut(s, ":")
+	if !found {
+		return Header{}, false
+	}
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return Header{}, false
+	}
+	return Header{Name: name, Value: strings.TrimSpace(value)}, true
+}
+
+// headerTagTarget returns the HeaderSet modified by the given VSL tag and whether the tag
+// is an unset
+func (t *Tx) headerTagTarget(tag string) (*HeaderSet, bool) {
+	switch tag {
+	case "ReqHeader":
+		return &t.ReqHeaders, false
+	case "ReqUnset":
+		return &t.ReqHeaders, true
+	case "RespHeader":
+		return &t.RespHeaders, false
+	case "RespUnset":
+		return &t.RespHeaders, true
+	case "BereqHeader":
+		return &t.BereqHeaders, false
+	case "BereqUnset":
+		return &t.BereqHeaders, true
+	case "BerespHeader":
+		return &t.BerespHeaders, false
+	case "BerespUnset":
+		return &t.BerespHeaders, true
+	case "ObjHeader":
+		return &t.ObjHeaders, false
+	}
+	return nil, false
+}
+
+// HeaderFamilies returns the header families of the tx with their names
+func (t *Tx) HeaderFamilies() []NamedHeaderSet {
+	return []NamedHeaderSet{
+		{Name: "Req", Set: &t.ReqHeaders},
+		{Name: "Resp", Set: &t.RespHeaders},
+		{Name: "Bereq", Set: &t.BereqHeaders},
+		{Name: "Beresp", Set: &t.BerespHeaders},
+		{Name: "Obj", Set: &t.ObjHeaders},
+	}
+}
+
+// NamedHeaderSet is a header family along with its name: Req, Resp, ...
+type NamedHeaderSet struct {
+	Name string
+	Set  *HeaderSet
+}
@@ -0,0 +1,49 @@
+package vsl
+
+import (
+	"bufio"
+	"io"
+	"strings"
+)
+
+// lineReader reads the input line by line like bufio.Scanner but without its
+// 64KB limit, a single big Cookie or URL would otherwise stop the parser
+type lineReader struct {
+	r    *bufio.Reader
+	line string
+	err  error
+}
+
+func newLineReader(r io.Reader) *lineReader {
+	return &lineReader{r: bufio.NewReader(r)}
+}
+
+// Scan advances to the next line, it returns false at the end of the input or on error
+func (l *lineReader) Scan() bool {
+	if l.err != nil {
+		return false
+	}
+
+	line, err := l.r.ReadString('\n')
+	if err != nil {
+		if err != io.EOF {
+			l.err = err
+		}
+		if line == "" || err != io.EOF {
+			return false
+		}
+	}
+
+	l.line = strings.TrimRight(line, "\r\n")
+	return true
+}
+
+// Text returns the last line read by Scan without the line terminator
+func (l *lineReader) Text() string {
+	return l.line
+}
+
+// Err returns the first error that is not io.EOF
+func (l *lineReader) Err() error {
+	return l.err
+}
@@ -0,0 +1,718 @@
+// Package vsl parses the text output of varnishlog into transactions.
+//
+// It only needs the output of varnishlog, so the logs can come from any
+// io.Reader: a local varnishlog process, ssh, docker exec or a file.
+package vsl
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ErrIncompleteTx is reported to OnError for the txs without an End record,
+// they are yielded anyway with Tx.Incomplete set
+var ErrIncompleteTx = errors.New("incomplete tx")
+
+// ParseError is a record or a block of records that could not be parsed
+type ParseError struct {
+	Vxid uint64 // Vxid of the tx, 0 if unknown
+	Line string // Offending line
+	Err  error
+}
+
+func (e *ParseError) Error() string {
+	return fmt.Sprintf("vxid %d: %s: %q", e.Vxid, e.Err.Error(), e.Line)
+}
+
+func (e *ParseError) Unwrap() error {
+	return e.Err
+}
+
+// Parser reads the text output of varnishlog and yields its transactions.
+// All the grouping modes are supported, the records of -g raw are grouped by vxid.
+//
+//	p := vsl.NewParser(os.Stdin)
+//	for p.Next() {
+//		tx := p.Tx()
+//		fmt.Println(tx.Txid, tx.Method, tx.Url)
+//	}
+//	if err := p.Err(); err != nil {
+//		log.Fatal(err)
+//	}
+type Parser struct {
+	// OnError is called with the records that could not be parsed,
+	// those records are skipped. It can be nil.
+	OnError func(err error)
+
+	scanner  *lineReader
+	tx       *Tx
+	err      error
+	grouping Grouping
+	raw      *rawAssembler
+	pending  [][]string // Blocks of raw txs ready to be parsed
+	// Group header that interrupted the previous tx
+	nextHeader string
+	stats      ParserStats
+}
+
+// ParserStats counts the blocks of the input that could not be fully parsed
+type ParserStats struct {
+	Incomplete int // Txs without an End record, yielded with Tx.Incomplete set
+	Malformed  int // Txs with records that could not be parsed and were skipped
+	Skipped    int // Blocks that could not be parsed at all
+}
+
+// NewParser returns a Parser reading from r
+func NewParser(r io.Reader) *Parser {
+	return &Parser{
+		scanner: newLineReader(r),
+		raw:     newRawAssembler(),
+	}
+}
+
+// Next advances to the next tx, which will then be available through Tx.
+// It returns false when there are no more txs, either by reaching the end of
+// the input or an error, which will be available through Err.
+func (p *Parser) Next() bool {
+	if p.err != nil {
+		return false
+	}
+
+	for {
+		// Blocks completed by the raw assembler
+		for len(p.pending) > 0 {
+			rawTx := p.pending[0]
+			p.pending = p.pending[1:]
+			if p.parse(rawTx) {
+				return true
+			}
+		}
+
+		var line string
+		if p.nextHeader != "" {
+			line, p.nextHeader = p.nextHeader, ""
+		} else if p.scanner.Scan() {
+			line = strings.TrimSpace(p.scanner.Text())
+		} else {
+			break
+		}
+		parts := strings.Fields(line)
+
+		if p.grouping == GroupingUnknown {
+			p.grouping = detectGrouping(parts)
+		}
+
+		if p.grouping == GroupingRaw {
+			if isRawRecord(parts) {
+				p.pending = append(p.pending, p.raw.add(line, parts)...)
+			}
+			continue
+		}
+
+		// Look for the start of a transaction, eg:
+		// *   << Session  >> 16812342
+		// **  << Request  >> 4
+		if !isGroupHeader(parts) {
+			continue
+		}
+		p.grouping = p.grouping.refine(parts)
+
+		rawTx := []string{line}
+		for p.scanner.Scan() {
+			line := strings.TrimSpace(p.scanner.Text())
+			parts := strings.Fields(line)
+
+			if isGroupHeader(parts) {
+				// The tx was cut (VSL overflow, killed varnishlog, ...), resync on the new one
+				p.nextHeader = line
+				break
+			}
+
+			rawTx = append(rawTx, line)
+			if len(parts) >= 2 && parts[1] == "End" {
+				// Tx ended (or an VSL store overflow was encountered)
+				break
+			}
+		}
+
+		if p.parse(rawTx) {
+			return true
+		}
+	}
+
+	if err := p.scanner.Err(); err != nil {
+		p.err = err
+		return false
+	}
+
+	// Raw txs whose End was not logged, eg: filtered out by a -q query
+	if p.grouping == GroupingRaw && len(p.raw.order) > 0 {
+		p.pending = p.raw.flush()
+		return p.Next()
+	}
+	return false
+}
+
+// parse parses a block of lines and makes it the current tx,
+// it returns false if the block was unusable
+func (p *Parser) parse(rawTx []string) bool {
+	tx, err := ParseTx(rawTx)
+	if err != nil {
+		if tx == nil {
+			p.stats.Skipped++
+		} else {
+			p.stats.Malformed++
+		}
+		p.reportError(err)
+	}
+	if tx == nil {
+		return false
+	}
+
+	if tx.Incomplete {
+		p.stats.Incomplete++
+		p.reportError(&ParseError{Vxid: tx.Vxid, Line: rawTx[0], Err: ErrIncompleteTx})
+	}
+	p.tx = tx
+	return true
+}
+
+func (p *Parser) reportError(err error) {
+	if p.OnError != nil {
+		p.OnError(err)
+	}
+}
+
+// Stats returns the number of txs that could not be fully parsed so far
+func (p *Parser) Stats() ParserStats {
+	return p.stats
+}
+
+// Grouping returns the grouping mode detected so far, the grouped modes are
+// told apart by the nesting of the txs so -g request may be reported as vxid
+// until the first nested tx is found
+func (p *Parser) Grouping() Grouping {
+	return p.grouping
+}
+
+// Tx returns the tx parsed by the last call to Next
+func (p *Parser) Tx() *Tx {
+	return p.tx
+}
+
+// Err returns the first error that stopped the parser, reaching the end of the input is not an error
+func (p *Parser) Err() error {
+	return p.err
+}
+
+// ParseTx parses the lines of a single tx, from its group header to its End record.
+// Records that cannot be parsed are skipped and reported in the returned error,
+// the tx is nil only when the whole block is unusable. Blocks without End are
+// parsed as well and marked as incomplete.
+func ParseTx(rawTx []string) (*Tx, error) {
+	currentTx := Tx{
+		RawTx: rawTx,
+	}
+
+	var (
+		errs       []error
+		transition VCLTransition
+		currentSub string // VCL_call running, empty between a VCL_return and the next VCL_call
+		lastURLs   = make(map[string]string)
+		// Header families whose original headers are already saved
+		frozenHeaders = make(map[*HeaderSet]bool)
+		ended         bool
+	)
+
+	recordError := func(line string, err error) {
+		errs = append(errs, &ParseError{Vxid: currentTx.Vxid, Line: line, Err: err})
+	}
+
+	for _, s := range rawTx {
+		parts := strings.Fields(s)
+		partsLen := len(parts)
+
+		if partsLen < 2 {
+			continue
+		}
+		if parts[1] == "End" {
+			ended = true
+		}
+
+		// Headers, the Host is also extracted below so don't skip the line yet
+		// -   ReqHeader      Accept: */*
+		// -   ReqUnset       Accept-Encoding: gzip, deflate
+		if set, unset := currentTx.headerTagTarget(parts[1]); set != nil {
+			if hdr, ok := parseHeader(recordValue(s)); ok {
+				action := "set"
+				if unset {
+					action = "unset"
+					set.Final = set.Final.remove(hdr.Name, hdr.Value)
+				} else {
+					set.Final = append(set.Final, hdr)
+				}
+				if currentSub != "" {
+					currentTx.addHeaderChange(HeaderChange{
+						Sub:    currentSub,
+						Family: headerTagFamily(parts[1]),
+						Action: action,
+						Name:   hdr.Name,
+						Value:  hdr.Value,
+					})
+				}
+			}
+		}
+
+		// Messages logged by VCL or varnish
+		// -   VCL_Log        cookie stripped
+		// -   VCL_Error      Not a valid url
+		if isEventTag(parts[1]) {
+			currentTx.Events = append(currentTx.Events, Event{
+				Tag:     parts[1],
+				Sub:     currentSub,
+				Message: recordValue(s),
+			})
+		}
+
+		currentTx.updateCacheOutcome(parts)
+		if err := currentTx.updateClient(parts); err != nil {
+			recordError(s, err)
+		}
+		if currentTx.RecordType == "bereq" {
+			currentTx.updateBackend(parts, recordValue(s))
+		}
+
+		// URL changes made by VCL, the URL itself is extracted below
+		// -   ReqURL         /path/to/resource
+		if partsLen >= 3 && (parts[1] == "ReqURL" || parts[1] == "BereqURL") {
+			family := headerTagFamily(parts[1])
+			url := recordValue(s)
+			if currentSub != "" {
+				currentTx.addHeaderChange(HeaderChange{
+					Sub:      currentSub,
+					Family:   family,
+					Action:   "url",
+					Value:    url,
+					OldValue: lastURLs[family],
+				})
+			}
+			lastURLs[family] = url
+		}
+
+		// New tx
+		// *   << Session  >> 16812342
+		// **  << Request  >> 4
+		if partsLen >= 5 && parts[0][0] == '*' {
+			vxid, err := strconv.ParseUint(parts[4], 10, 64)
+			if err != nil {
+				recordError(s, fmt.Errorf("unparsable vxid: %w", err))
+				return nil, errors.Join(errs...)
+			}
+			currentTx.Vxid = vxid
+			continue
+		}
+
+		// RecordType & reason - the parent cannot be set here because we don't know if it was an ESI
+		// -   Begin          bereq 21334348 fetch
+		// --  Begin          req 2 esi 1
+		// -4- Begin          bereq 6 fetch
+		if partsLen > 4 && parts[1] == "Begin" {
+			if parts[4] == "esi" && len(parts) > 5 {
+				currentTx.Txid = fmt.Sprintf("%d_%s", currentTx.Vxid, parts[5])
+			} else {
+				currentTx.Txid = fmt.Sprintf("%d", currentTx.Vxid)
+			}
+
+			currentTx.RecordType = parts[2]
+			currentTx.Reason = parts[4]
+			continue
+		}
+
+		// Children
+		// --  Link           bereq 12 fetch
+		// --  Link           req 13 esi 2
+		if partsLen > 4 && parts[1] == "Link" {
+			link := Link{RecordType: parts[2], Reason: parts[4]}
+			if parts[4] == "esi" && len(parts) > 5 {
+				link.Txid = fmt.Sprintf("%s_%s", parts[3], parts[5])
+			} else {
+				link.Txid = parts[3]
+			}
+			vxid, err := strconv.ParseUint(parts[3], 10, 64)
+			if err != nil {
+				recordError(s, fmt.Errorf("unparsable link vxid: %w", err))
+				continue
+			}
+			link.Vxid = vxid
+			currentTx.Links = append(currentTx.Links, link)
+			continue
+		}
+
+		// URL (want the first value in the VSL so check if we don't have a URL yet)
+		// -   ReqURL         /path/to/resource
+		if currentTx.Url == "" && partsLen == 3 && parts[1] == "ReqURL" {
+			currentTx.Url = parts[2]
+			continue
+		}
+
+		// BereqURL (want the last value since it's what the backend receives)
+		// -   BereqURL         /path/to/resource
+		if partsLen == 3 && parts[1] == "BereqURL" {
+			currentTx.Url = parts[2]
+			continue
+		}
+
+		// Host (want the first value)
+		// -   ReqHeader      host: www.example.com
+		// -   ReqHeader      Host:www.example.com
+		if currentTx.Host == "" && partsLen >= 3 && parts[1] == "ReqHeader" && strings.HasPrefix(strings.ToLower(parts[2]), "host:") {
+			if partsLen == 4 {
+				currentTx.Host = parts[3]
+				continue
+			} else {
+				hostParts := strings.Split(parts[2], ":")
+				if len(hostParts) >= 2 {
+					currentTx.Host = hostParts[1]
+					continue
+				}
+			}
+		}
+
+		// BereqHost (want the last value)
+		// -   BereqHeader      host: www.example.com
+		// -   BereqHeader      Host:www.example.com
+		if partsLen >= 3 && parts[1] == "BereqHeader" && strings.HasPrefix(strings.ToLower(parts[2]), "host:") {
+			if partsLen == 4 {
+				currentTx.Host = parts[3]
+				continue
+			} else {
+				hostParts := strings.Split(parts[2], ":")
+				if len(hostParts) >= 2 {
+					currentTx.Host = hostParts[1]
+					continue
+				}
+			}
+		}
+
+		// Method
+		// -   ReqMethod      GET
+		if partsLen == 3 && (parts[1] == "ReqMethod" || parts[1] == "BereqMethod") {
+			currentTx.Method = parts[2]
+			continue
+		}
+
+		// Status (last one)
+		// --  RespStatus     301
+		// --- BerespStatus   200
+		if partsLen == 3 && (parts[1] == "RespStatus" || parts[1] == "BerespStatus") {
+			statusCode, err := strconv.Atoi(parts[2])
+			if err == nil {
+				currentTx.StatusCode = statusCode
+			}
+			continue
+		}
+
+		// StatusReason (last one)
+		// --  RespReason     OK
+		// --- BerespReason   OK
+		if partsLen == 3 && (parts[1] == "RespReason" || parts[1] == "BerespReason") {
+			currentTx.StatusReason = strings.Join(parts[2:], " ")
+			continue
+		}
+
+		// Timestamp
+		//                    label absolute        sinceStart sinceLast
+		// -   Timestamp      Resp: 1714823222.274262 0.003330 0.000015
+		if partsLen > 5 && parts[1] == "Timestamp" {
+			ts, err := newTimestamp(
+				strings.TrimSuffix(parts[2], ":"), // Remove ':' from the label
+				parts[3],
+				parts[4],
+				parts[5],
+			)
+			if err != nil {
+				recordError(s, err)
+			} else {
+				currentTx.Timestamps = append(currentTx.Timestamps, *ts)
+			}
+			continue
+		}
+
+		// TTL
+		// --  TTL            RFC 120 10 0 1606398419 1606398419 1606398419 0 0 cacheable
+		// --  TTL            VCL 120 10 0 1606400537 uncacheable
+		// --  TTL            HFP 10 0 0 1606402666 uncacheable
+		if parts[1] == "TTL" && (partsLen == 12 || partsLen == 8) {
+			ttl, err := newTTL(parts[2:])
+			if err != nil {
+				recordError(s, err)
+			} else {
+				currentTx.TTL = append(currentTx.TTL, *ttl)
+			}
+			continue
+		}
+
+		// Transitions
+		// --  VCL_call       RECV
+		if partsLen == 3 && parts[1] == "VCL_call" {
+			transition = VCLTransition{Call: parts[2]}
+			currentSub = parts[2]
+			// Everything logged until now for a family are the original headers
+			for _, hs := range currentTx.HeaderFamilies() {
+				if !frozenHeaders[hs.Set] && len(hs.Set.Final) > 0 {
+					hs.Set.Original = slices.Clone(hs.Set.Final)
+					frozenHeaders[hs.Set] = true
+				}
+			}
+			continue
+		}
+		// --  VCL_return     synth
+		if partsLen == 3 && parts[1] == "VCL_return" {
+			transition.Return = parts[2]
+			currentSub = ""
+			currentTx.Transitions = append(currentTx.Transitions, transition)
+			continue
+		}
+
+		// Accounting information
+		// h=header, b=body, t=total
+		//                    received-|-transmitted
+		//                    h   b t   h   b t
+		// --  ReqAcct        611 0 611 287 0 287
+		// --- BereqAcct      619 0 619 536 935300 935836
+		if partsLen == 8 && (parts[1] == "ReqAcct" || parts[1] == "BereqAcct") {
+			var (
+				sizes [4]int64
+				err   error
+			)
+			for i, idx := range []int{2, 3, 5, 6} {
+				if sizes[i], err = strconv.ParseInt(parts[idx], 10, 64); err != nil {
+					break
+				}
+			}
+			if err != nil {
+				// Partial values would report wrong byte counts
+				recordError(s, fmt.Errorf("unparsable accounting field: %w", err))
+				continue
+			}
+			currentTx.Accounting = RequestAccounting{
+				HeaderBytesReceived:    sizes[0],
+				BodyBytesReceived:      sizes[1],
+				HeaderBytesTransmitted: sizes[2],
+				BodyBytesTransmitted:   sizes[3],
+			}
+
+			continue
+		}
+
+		// Special handling for sessions
+		if currentTx.RecordType == "sess" {
+			// Timestamp
+			// -   SessClose      REM_CLOSE 0.000
+			if partsLen == 4 && parts[1] == "SessClose" {
+				ts, err := newTimestamp(
+					parts[2],
+					"0",
+					parts[3],
+					parts[3],
+				)
+				if err != nil {
+					recordError(s, err)
+				} else {
+					currentTx.Timestamps = append(currentTx.Timestamps, *ts)
+				}
+			}
+
+			if currentTx.Method == "" {
+				currentTx.Method = "-"
+			}
+			if currentTx.Host == "" {
+				currentTx.Host = "-"
+			}
+			if currentTx.Url == "" {
+				currentTx.Url = "-"
+			}
+			// Looking for:
+			// -   VSL            store overflow
+			if len(parts) >= 4 && parts[1] == "VSL" && parts[3] == "overflow" {
+				currentTx.Host = "store overflow"
+				continue
+			}
+		}
+	}
+
+	currentTx.setForwardedFor()
+
+	// Families not modified by any VCL subroutine
+	for _, hs := range currentTx.HeaderFamilies() {
+		if !frozenHeaders[hs.Set] {
+			hs.Set.Original = slices.Clone(hs.Set.Final)
+		}
+	}
+
+	currentTx.Incomplete = !ended
+	return &currentTx, errors.Join(errs...)
+}
+
+// recordValue returns the value of a VSL line, everything after the tag, keeping its spacing
+//
+//	--  ReqHeader      Cookie: a=1;  b=2
+func recordValue(line string) string {
+	return skipFields(line, 2)
+}
+
+// skipFields returns the line without its first n fields, keeping the spacing of the rest
+func skipFields(line string, n int) string {
+	line = strings.TrimSpace(line)
+	for i := 0; i < n; i++ {
+		idx := strings.IndexAny(line, " \t")
+		if idx < 0 {
+			return ""
+		}
+		line = strings.TrimLeft(line[idx:], " \t")
+	}
+	return line
+}
+
+func newTimestamp(label, absStr, sinceStartStr, sinceLastStr string) (*Timestamp, error) {
+	absoluteTime, err := parseUnixTimestamp(absStr)
+	if err != nil {
+		return nil, fmt.Errorf("invalid Timestamp: unparsable absoluteTime field: %w", err)
+	}
+
+	// Convert sinceStart and sinceLast from string to float64
+	sinceStartFloat, err := strconv.ParseFloat(sinceStartStr, 64)
+	if err != nil {
+		return nil, fmt.Errorf("invalid Timestamp: unparsable sinceStart field: %w", err)
+	}
+	sinceLastFloat, err := strconv.ParseFloat(sinceLastStr, 64)
+	if err != nil {
+		return nil, fmt.Errorf("invalid Timestamp: unparsable sinceLast field: %w", err)
+	}
+
+	// Convert the float64 values to time.Duration
+	sinceStart := time.Duration(sinceStartFloat * float64(time.Second))
+	sinceLast := time.Duration(sinceLastFloat * float64(time.Second))
+
+	return &Timestamp{
+		EventLabel: label,
+		Absolute:   absoluteTime,
+		SinceStart: sinceStart,
+		SinceLast:  sinceLast,
+	}, nil
+}
+
+func newTTL(parts []string) (*TTLData, error) {
+	// RFC 120 10 0 1606398419 1606398419 1606398419 0 0 cacheable
+	// VCL 120 10 0 1606400537 uncacheable
+	// HFP 10 0 0 1606402666 uncacheable
+
+	ttlData := TTLData{Source: parts[0]}
+
+	// First 5 parts are common
+	ttl, err := strconv.Atoi(parts[1])
+	if err != nil {
+		return nil, fmt.Errorf("invalid TTL: unparsable ttl field: %w", err)
+	}
+	ttlData.TTL = ttl
+
+	grace, err := strconv.Atoi(parts[2])
+	if err != nil {
+		return nil, fmt.Errorf("invalid TTL: unparsable grace field: %w", err)
+	}
+	ttlData.Grace = grace
+
+	keep, err := strconv.Atoi(parts[3])
+	if err != nil {
+		return nil, fmt.Errorf("invalid TTL: unparsable keep field: %w", err)
+	}
+	ttlData.Keep = keep
+
+	ref, err := parseUnixTimestamp(parts[4])
+	if err != nil {
+		return nil, fmt.Errorf("invalid TTL: unparsable reference field: %w", err)
+	}
+	ttlData.Reference = ref
+
+	// Check if we are parsing a VCL or HFP source (6 fields) or a HFP
+	if len(parts) == 6 {
+		ttlData.CacheStatus = parts[5]
+		return &ttlData, nil
+	}
+
+	if len(parts) != 10 {
+		return nil, fmt.Errorf("invalid TTL: unexpected number of fields: %d", len(parts))
+	}
+
+	age, err := strconv.Atoi(parts[5])
+	if err != nil {
+		return nil, fmt.Errorf("invalid TTL: unparsable age field: %w", err)
+	}
+	ttlData.Age = age
+
+	date, err := parseUnixTimestamp(parts[6])
+	if err != nil {
+		return nil, fmt.Errorf("invalid TTL: unparsable date field: %w", err)
+	}
+	ttlData.Date = date
+
+	expires, err := parseUnixTimestamp(parts[7])
+	if err != nil {
+		return nil, fmt.Errorf("invalid TTL: unparsable expires field: %w", err)
+	}
+	ttlData.Expires = expires
+
+	maxAge, err := strconv.Atoi(parts[8])
+	if err != nil {
+		return nil, fmt.Errorf("invalid TTL: unparsable maxAge field: %w", err)
+	}
+	ttlData.MaxAge = maxAge
+
+	// Last field
+	ttlData.CacheStatus = parts[9]
+
+	return &ttlData, nil
+}
+
+// parseUnixTimestamp converts a Unix timestamp string (integer or fractional) to a time.Time object
+func parseUnixTimestamp(timestampStr string) (time.Time, error) {
+	// Check if the timestamp contains a decimal point
+	if strings.Contains(timestampStr, ".") {
+		// Split the string into seconds and fractional parts
+		parts := strings.SplitN(timestampStr, ".", 2)
+		if len(parts) != 2 {
+			return time.Time{}, fmt.Errorf("invalid timestamp format: %s", timestampStr)
+		}
+
+		// Convert the seconds part to an int64
+		seconds, err := strconv.ParseInt(parts[0], 10, 64)
+		if err != nil {
+			return time.Time{}, fmt.Errorf("error converting seconds part: %v", err)
+		}
+
+		// Convert the fractional part to nanoseconds
+		fractionalPart := parts[1]
+		if len(fractionalPart) > 9 {
+			fractionalPart = fractionalPart[:9]
+		}
+		nanoseconds, err := strconv.ParseInt(fractionalPart+strings.Repeat("0", 9-len(fractionalPart)), 10, 64)
+		if err != nil {
+			return time.Time{}, fmt.Errorf("error converting fractional part: %v", err)
+		}
+
+		return time.Unix(seconds, nanoseconds), nil
+	}
+
+	// Integer timestamp format
+	timestampInt, err := strconv.ParseInt(timestampStr, 10, 64)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("error converting timestamp: %v", err)
+	}
+
+	return time.Unix(timestampInt, 0), nil
+}
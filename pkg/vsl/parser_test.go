@@ -0,0 +1,523 @@
+package vsl
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"testing"
+)
+
+const testReqLog = `*   << Request  >> 32770
+-   Begin          req 32769 rxreq
+-   Timestamp      Start: 1714823222.270932 0.000000 0.000000
+-   Timestamp      Req: 1714823222.270932 0.000000 0.000000
+-   VCL_use        boot
+-   ReqStart       192.168.50.1 50312 a0
+-   ReqMethod      GET
+-   ReqURL         /esi/?utm_source=x
+-   ReqProtocol    HTTP/1.1
+-   ReqHeader      Host: www.example1.com
+-   ReqHeader      User-Agent: curl/8.5.0
+-   ReqHeader      Accept: */*
+-   ReqHeader      Cookie: a=1;  b=2
+-   ReqHeader      X-Forwarded-For: 192.168.50.1
+-   VCL_call       RECV
+-   ReqURL         /esi/
+-   ReqUnset       Cookie: a=1;  b=2
+-   ReqHeader      X-Foo: bar
+-   ReqUnset       Accept: */*
+-   ReqHeader      Accept: text/html
+-   VCL_return     hash
+-   VCL_call       HASH
+-   VCL_return     lookup
+-   VCL_call       MISS
+-   VCL_return     fetch
+-   Link           bereq 32771 fetch
+-   Timestamp      Fetch: 1714823222.274262 0.003330 0.003330
+-   RespProtocol   HTTP/1.1
+-   RespStatus     200
+-   RespReason     OK
+-   RespHeader     Date: Sat, 04 May 2024 11:47:02 GMT
+-   RespHeader     Content-Type: text/html
+-   RespHeader     X-Varnish: 32770
+-   VCL_call       DELIVER
+-   RespUnset      X-Varnish: 32770
+-   VCL_return     deliver
+-   Timestamp      Process: 1714823222.274270 0.003338 0.000008
+-   Timestamp      Resp: 1714823222.274285 0.003353 0.000015
+-   ReqAcct        84 0 84 222 13 235
+-   End
+`
+
+func parseTestLog(t *testing.T, log string) *Tx {
+	t.Helper()
+	rawTx := strings.Split(strings.TrimSpace(log), "\n")
+	tx, err := ParseTx(rawTx)
+	if err != nil {
+		t.Fatalf("ParseTx returned an error: %s", err)
+	}
+	if tx == nil {
+		t.Fatal("ParseTx returned nil")
+	}
+	return tx
+}
+
+// TestParseTxHeaders tests that the header families keep the original and final headers.
+func TestParseTxHeaders(t *testing.T) {
+	tx := parseTestLog(t, testReqLog)
+
+	expectedOriginal := Headers{
+		{Name: "Host", Value: "www.example1.com"},
+		{Name: "User-Agent", Value: "curl/8.5.0"},
+		{Name: "Accept", Value: "*/*"},
+		{Name: "Cookie", Value: "a=1;  b=2"},
+		{Name: "X-Forwarded-For", Value: "192.168.50.1"},
+	}
+	if !slices.Equal(tx.ReqHeaders.Original, expectedOriginal) {
+		t.Errorf("Expected original req headers:\n%v\nGot:\n%v", expectedOriginal, tx.ReqHeaders.Original)
+	}
+
+	expectedFinal := Headers{
+		{Name: "Host", Value: "www.example1.com"},
+		{Name: "User-Agent", Value: "curl/8.5.0"},
+		{Name: "X-Forwarded-For", Value: "192.168.50.1"},
+		{Name: "X-Foo", Value: "bar"},
+		{Name: "Accept", Value: "text/html"},
+	}
+	if !slices.Equal(tx.ReqHeaders.Final, expectedFinal) {
+		t.Errorf("Expected final req headers:\n%v\nGot:\n%v", expectedFinal, tx.ReqHeaders.Final)
+	}
+
+	if got := tx.RespHeaders.Final.Get("x-varnish"); got != "" {
+		t.Errorf("Expected X-Varnish to be unset from the final resp headers, got %q", got)
+	}
+	if got := tx.RespHeaders.Original.Get("X-Varnish"); got != "32770" {
+		t.Errorf("Expected X-Varnish 32770 in the original resp headers, got %q", got)
+	}
+	if tx.BereqHeaders.Modified() || len(tx.BereqHeaders.Final) > 0 {
+		t.Errorf("Expected no bereq headers, got %v", tx.BereqHeaders)
+	}
+}
+
+// TestParseTxHeaderChanges tests that the changes are attached to the running VCL subroutine.
+func TestParseTxHeaderChanges(t *testing.T) {
+	tx := parseTestLog(t, testReqLog)
+
+	timeline := tx.HeaderTimeline()
+	expected := []string{
+		"vcl_recv url req /esi/, unset req Cookie, set req X-Foo, set req Accept",
+		"vcl_deliver unset resp X-Varnish",
+	}
+	if len(timeline) != len(expected) {
+		t.Fatalf("Expected %d subroutines, got %d: %v", len(expected), len(timeline), timeline)
+	}
+	for i, sc := range timeline {
+		if sc.String() != expected[i] {
+			t.Errorf("Expected %q, got %q", expected[i], sc.String())
+		}
+	}
+
+	accept := timeline[0].Changes[3]
+	if accept.Value != "text/html" || accept.OldValue != "*/*" {
+		t.Errorf("Expected Accept to be replaced from */* to text/html, got %+v", accept)
+	}
+	url := timeline[0].Changes[0]
+	if url.OldValue != "/esi/?utm_source=x" {
+		t.Errorf("Expected the previous url to be kept, got %+v", url)
+	}
+}
+
+// TestParseTxCacheOutcome tests the cache outcome classification.
+func TestParseTxCacheOutcome(t *testing.T) {
+	tests := []struct {
+		name     string
+		log      string
+		expected CacheOutcome
+		hitVxid  uint64
+	}{
+		{name: "miss", log: testReqLog, expected: OutcomeMiss},
+		{
+			name: "hit",
+			log: `*   << Request  >> 5
+-   Begin          req 4 rxreq
+-   VCL_call       RECV
+-   VCL_return     hash
+-   VCL_call       HASH
+-   VCL_return     lookup
+-   Hit            32771 119.993 10.000 0.000
+-   VCL_call       HIT
+-   VCL_return     deliver
+-   End`,
+			expected: OutcomeHit,
+			hitVxid:  32771,
+		},
+		{
+			name: "hit-for-pass",
+			log: `*   << Request  >> 5
+-   Begin          req 4 rxreq
+-   VCL_call       RECV
+-   VCL_return     hash
+-   HitPass        32773 119.993
+-   VCL_call       PASS
+-   VCL_return     fetch
+-   Link           bereq 6 pass
+-   End`,
+			expected: OutcomeHitPass,
+			hitVxid:  32773,
+		},
+		{
+			name: "synth",
+			log: `*   << Request  >> 5
+-   Begin          req 4 rxreq
+-   VCL_call       RECV
+-   VCL_return     synth
+-   VCL_call       SYNTH
+-   VCL_return     deliver
+-   End`,
+			expected: OutcomeSynth,
+		},
+	}
+
+	for _, tt := range tests {
+		tx := parseTestLog(t, tt.log)
+		if tx.CacheOutcome != tt.expected || tx.HitVxid != tt.hitVxid {
+			t.Errorf("%s: expected %s (%d), got %s (%d)", tt.name, tt.expected, tt.hitVxid, tx.CacheOutcome, tx.HitVxid)
+		}
+	}
+}
+
+const testBereqLog = `**  << BeReq    >> 32771
+--  Begin          bereq 32770 fetch
+--  VCL_use        boot
+--  Timestamp      Start: 1714823222.271010 0.000000 0.000000
+--  BereqMethod    GET
+--  BereqURL       /esi/
+--  BereqProtocol  HTTP/1.1
+--  BereqHeader    Host: www.example1.com
+--  VCL_call       BACKEND_FETCH
+--  VCL_return     fetch
+--  BackendOpen    26 boot.default 192.168.50.11 80 192.168.50.10 45872 reuse
+--  Timestamp      Bereq: 1714823222.271102 0.000092 0.000092
+--  FetchError     HTC eof (-1)
+--  BerespProtocol HTTP/1.1
+--  BerespStatus   200
+--  BerespReason   OK
+--  BerespHeader   Content-Length: 13
+--  VCL_call       BACKEND_RESPONSE
+--  VCL_return     deliver
+--  Fetch_Body     3 length stream
+--  BackendClose   26 boot.default recycle
+--  Timestamp      BerespBody: 1714823222.274199 0.003189 0.000058
+--  BereqAcct      124 0 124 128 13 141
+--  End
+`
+
+// TestParseTxBackend tests the backend connection details of a bereq.
+func TestParseTxBackend(t *testing.T) {
+	tx := parseTestLog(t, testBereqLog)
+
+	expected := BackendConnection{
+		Name:        "boot.default",
+		RemoteAddr:  "192.168.50.11:80",
+		LocalAddr:   "192.168.50.10:45872",
+		Reused:      true,
+		CloseReason: "recycle",
+		FetchBody:   "length stream",
+		FetchErrors: []string{"HTC eof (-1)"},
+	}
+	got := tx.Backend
+	if got.Name != expected.Name || got.RemoteAddr != expected.RemoteAddr || got.LocalAddr != expected.LocalAddr ||
+		got.Reused != expected.Reused || got.CloseReason != expected.CloseReason || got.FetchBody != expected.FetchBody ||
+		!slices.Equal(got.FetchErrors, expected.FetchErrors) {
+		t.Errorf("Expected backend:\n%+v\nGot:\n%+v", expected, got)
+	}
+
+	summary := "backend boot.default 192.168.50.11:80 (reused, closed: recycle) body: length stream fetch error: HTC eof (-1)"
+	if tx.BackendSummary() != summary {
+		t.Errorf("Expected summary %q, got %q", summary, tx.BackendSummary())
+	}
+}
+
+// TestParseTxClient tests the client info of sessions and requests.
+func TestParseTxClient(t *testing.T) {
+	sess := parseTestLog(t, `*   << Session  >> 32769
+-   Begin          sess 0 HTTP/1
+-   SessOpen       192.168.50.1 50312 a0 192.168.50.10 80 1714823222.270891 23
+-   Link           req 32770 rxreq
+-   SessClose      REM_CLOSE 0.004
+-   End`)
+	if sess.Client.Addr() != "192.168.50.1:50312" || sess.Client.Listener != "a0" || sess.Client.LocalAddr != "192.168.50.10:80" {
+		t.Errorf("Unexpected session client: %+v", sess.Client)
+	}
+	if sess.Client.OpenTime.Unix() != 1714823222 {
+		t.Errorf("Unexpected session open time: %s", sess.Client.OpenTime)
+	}
+
+	req := parseTestLog(t, testReqLog)
+	if req.Client.Addr() != "192.168.50.1:50312" || req.Client.Protocol != "HTTP/1.1" {
+		t.Errorf("Unexpected request client: %+v", req.Client)
+	}
+	if !slices.Equal(req.Client.XForwardedFor, []string{"192.168.50.1"}) {
+		t.Errorf("Unexpected X-Forwarded-For chain: %v", req.Client.XForwardedFor)
+	}
+
+	req.InheritClient(sess)
+	if req.Client.LocalAddr != "192.168.50.10:80" || req.Client.OpenTime != sess.Client.OpenTime {
+		t.Errorf("Expected the request to inherit the session fields, got %+v", req.Client)
+	}
+}
+
+// TestParseTxAccounting tests that an accounting record with an unparsable field is skipped.
+func TestParseTxAccounting(t *testing.T) {
+	tx, _ := ParseTx([]string{
+		"*   << Request  >> 32770",
+		"-   Begin          req 32769 rxreq",
+		"-   ReqAcct        84 0 84 222 x 235",
+		"-   End",
+	})
+	if tx == nil || tx.Accounting != (RequestAccounting{}) {
+		t.Errorf("Expected no accounting, got %+v", tx)
+	}
+}
+
+// TestParseTxEvents tests that the events keep their VCL subroutine.
+func TestParseTxEvents(t *testing.T) {
+	tx := parseTestLog(t, `*   << Request  >> 5
+-   Begin          req 4 rxreq
+-   Debug          "RES_MODE 2"
+-   VCL_call       RECV
+-   VCL_Log        cookie  stripped
+-   VCL_Error      Not a valid url
+-   VCL_return     synth
+-   VCL_call       SYNTH
+-   VCL_return     deliver
+-   Error          out of workspace (req)
+-   End`)
+
+	expected := []Event{
+		{Tag: "Debug", Sub: "", Message: `"RES_MODE 2"`},
+		{Tag: "VCL_Log", Sub: "RECV", Message: "cookie  stripped"},
+		{Tag: "VCL_Error", Sub: "RECV", Message: "Not a valid url"},
+		{Tag: "Error", Sub: "", Message: "out of workspace (req)"},
+	}
+	if !slices.Equal(tx.Events, expected) {
+		t.Errorf("Expected events:\n%+v\nGot:\n%+v", expected, tx.Events)
+	}
+
+	errors, logs := tx.CountEvents()
+	if errors != 2 || logs != 2 {
+		t.Errorf("Expected 2 errors and 2 logs, got %d and %d", errors, logs)
+	}
+}
+
+// TestParser tests that the parser yields every tx of a request group and reports broken blocks.
+func TestParser(t *testing.T) {
+	input := testReqLog + "\n" + testBereqLog + `
+*   << Request  >> notanumber
+-   Begin          req 1 rxreq
+-   End
+
+`
+	var parseErrors []error
+	p := NewParser(strings.NewReader(input))
+	p.OnError = func(err error) {
+		parseErrors = append(parseErrors, err)
+	}
+
+	var txids []string
+	for p.Next() {
+		txids = append(txids, p.Tx().Txid)
+	}
+	if err := p.Err(); err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+
+	if !slices.Equal(txids, []string{"32770", "32771"}) {
+		t.Errorf("Expected txs 32770 and 32771, got %v", txids)
+	}
+	if len(parseErrors) != 1 {
+		t.Errorf("Expected 1 parse error, got %v", parseErrors)
+	}
+
+	req := parseTestLog(t, testReqLog)
+	expectedLinks := []Link{{Txid: "32771", Vxid: 32771, RecordType: "bereq", Reason: "fetch"}}
+	if !slices.Equal(req.Links, expectedLinks) {
+		t.Errorf("Expected links %v, got %v", expectedLinks, req.Links)
+	}
+}
+
+// TestParserIncompleteTx tests that cut txs are yielded as incomplete and the parser resyncs on the next one.
+func TestParserIncompleteTx(t *testing.T) {
+	input := `*   << Request  >> 5
+-   Begin          req 4 rxreq
+-   ReqURL         /cut
+*   << Request  >> 7
+-   Begin          req 6 rxreq
+-   ReqURL         /complete
+-   End
+*   << Request  >> 9
+-   Begin          req 8 rxreq
+-   ReqURL         /truncated
+`
+	var parseErrors []error
+	p := NewParser(strings.NewReader(input))
+	p.OnError = func(err error) {
+		parseErrors = append(parseErrors, err)
+	}
+
+	var txs []*Tx
+	for p.Next() {
+		txs = append(txs, p.Tx())
+	}
+	if err := p.Err(); err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	if len(txs) != 3 {
+		t.Fatalf("Expected 3 txs, got %d", len(txs))
+	}
+
+	expected := []struct {
+		url        string
+		incomplete bool
+	}{
+		{"/cut", true},
+		{"/complete", false},
+		{"/truncated", true},
+	}
+	for i, e := range expected {
+		if txs[i].Url != e.url || txs[i].Incomplete != e.incomplete {
+			t.Errorf("Expected tx %s incomplete=%v, got %s incomplete=%v", e.url, e.incomplete, txs[i].Url, txs[i].Incomplete)
+		}
+	}
+
+	if stats := p.Stats(); stats != (ParserStats{Incomplete: 2}) {
+		t.Errorf("Unexpected stats: %+v", stats)
+	}
+	if len(parseErrors) != 2 || !errors.Is(parseErrors[0], ErrIncompleteTx) {
+		t.Errorf("Expected 2 ErrIncompleteTx errors, got %v", parseErrors)
+	}
+}
+
+const testRawLog = `         0 CLI            - Rd ping
+     32770 Begin          c req 32769 rxreq
+     32770 ReqMethod      c GET
+     32770 ReqURL         c /index.html
+     32771 Begin          b bereq 32770 fetch
+     32771 BereqMethod    b GET
+     32770 ReqHeader      c Host: www.example.com
+     32770 Link           c bereq 32771 fetch
+     32771 BerespStatus   b 200
+     32771 End            b
+     32770 RespStatus     c 200
+     32770 End            c
+     32772 ReqURL         c /filtered
+`
+
+// TestParserRaw tests that the interleaved records of -g raw are grouped by vxid
+func TestParserRaw(t *testing.T) {
+	p := NewParser(strings.NewReader(testRawLog))
+
+	var txs []*Tx
+	for p.Next() {
+		txs = append(txs, p.Tx())
+	}
+	if err := p.Err(); err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	if p.Grouping() != GroupingRaw {
+		t.Errorf("Expected raw grouping, got %s", p.Grouping())
+	}
+	if len(txs) != 3 {
+		t.Fatalf("Expected 3 txs, got %d", len(txs))
+	}
+
+	bereq, req, filtered := txs[0], txs[1], txs[2]
+	if bereq.Txid != "32771" || bereq.RecordType != "bereq" || bereq.Method != "GET" || bereq.StatusCode != 200 {
+		t.Errorf("Unexpected bereq: %+v", bereq)
+	}
+	if req.Txid != "32770" || req.RecordType != "req" || req.Reason != "rxreq" ||
+		req.Url != "/index.html" || req.Host != "www.example.com" || len(req.Links) != 1 {
+		t.Errorf("Unexpected req: %+v", req)
+	}
+	// Begin is rebuilt when filtered out and the tx is marked as incomplete
+	if filtered.Txid != "32772" || filtered.RecordType != "req" || filtered.Reason != "unknown" || filtered.Url != "/filtered" || !filtered.Incomplete {
+		t.Errorf("Unexpected filtered tx: %+v", filtered)
+	}
+}
+
+// TestRawAssemblerPending tests that a raw tx that never ends is evicted first and
+// does not keep the completed ones around.
+func TestRawAssemblerPending(t *testing.T) {
+	a := newRawAssembler()
+	add := func(line string) [][]string {
+		return a.add(line, strings.Fields(line))
+	}
+
+	add("1 Begin c req 0 rxreq")
+	for vxid := 2; vxid < 3*maxPendingRawTxs; vxid++ {
+		add(fmt.Sprintf("%d Begin c req 1 rxreq", vxid))
+		if done := add(fmt.Sprintf("%d End c", vxid)); len(done) != 1 {
+			t.Fatalf("Expected tx %d to be done, got %v", vxid, done)
+		}
+	}
+	if len(a.groups) != 1 || len(a.order) >= 2*maxPendingRawTxs {
+		t.Errorf("Expected 1 pending tx and the order compacted, got %d and %d", len(a.groups), len(a.order))
+	}
+
+	var done [][]string
+	for vxid := 3 * maxPendingRawTxs; len(done) == 0; vxid++ {
+		done = add(fmt.Sprintf("%d Begin c req 1 rxreq", vxid))
+	}
+	if len(done) != 1 || done[0][0] != "*   << Request >> 1" {
+		t.Errorf("Expected the oldest tx to be evicted, got %v", done)
+	}
+}
+
+// TestParserGrouping tests the detection of the grouped modes
+func TestParserGrouping(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected Grouping
+	}{
+		{"*   << Request  >> 5\n-   Begin          req 4 rxreq\n-   End\n", GroupingVxid},
+		{testReqLog + "\n" + testBereqLog, GroupingRequest},
+		{"*   << Session  >> 1\n-   Begin          sess 0 HTTP/1\n-   End\n", GroupingSession},
+		{testRawLog, GroupingRaw},
+	}
+
+	for _, test := range tests {
+		p := NewParser(strings.NewReader(test.input))
+		for p.Next() {
+		}
+		if p.Grouping() != test.expected {
+			t.Errorf("Expected %s grouping, got %s", test.expected, p.Grouping())
+		}
+	}
+}
+
+// TestParserLongLines tests that records longer than the 64KB limit of bufio.Scanner are parsed
+func TestParserLongLines(t *testing.T) {
+	cookie := "a=" + strings.Repeat("x", 256*1024)
+	url := "/" + strings.Repeat("y", 128*1024)
+	input := "*   << Request  >> 5\r\n" +
+		"-   Begin          req 4 rxreq\r\n" +
+		"-   ReqURL         " + url + "\r\n" +
+		"-   ReqHeader      Cookie: " + cookie + "\r\n" +
+		"-   End" // No trailing newline
+
+	p := NewParser(strings.NewReader(input))
+	if !p.Next() {
+		t.Fatalf("Expected a tx, got error %v", p.Err())
+	}
+	tx := p.Tx()
+	if tx.Url != url {
+		t.Errorf("Expected url of %d bytes, got %d bytes", len(url), len(tx.Url))
+	}
+	if got := tx.ReqHeaders.Final.Get("Cookie"); got != cookie {
+		t.Errorf("Expected cookie of %d bytes, got %d bytes", len(cookie), len(got))
+	}
+	if tx.Incomplete {
+		t.Errorf("Expected a complete tx")
+	}
+}
@@ -0,0 +1,500 @@
+package vsl
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// Query is a parsed VSL query, the language of varnishlog -q, evaluated on parsed txs.
+//
+//	ReqURL ~ "^/api" and RespStatus >= 500
+//	{2+}BerespHeader:Cache-Control ~ "private"
+//	Timestamp:Resp[3] > 0.5 or not ReqHeader:User-Agent
+//
+// Like in varnishlog the query is evaluated on a whole group of txs: each record
+// selector matches if any record of any tx of the group matches, so the operands
+// of and/or can be satisfied by different txs. Regular expressions use the RE2
+// syntax of Go instead of PCRE.
+type Query struct {
+	expr string
+	root queryNode
+}
+
+// ParseQuery parses a VSL query, the error includes the column of the problem
+func ParseQuery(expr string) (*Query, error) {
+	tokens, err := lexQuery(expr)
+	if err != nil {
+		return nil, err
+	}
+
+	p := &queryParser{tokens: tokens}
+	root, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if tok := p.peek(); tok.kind != qtokEOF {
+		return nil, tok.errorf("unexpected %s", tok)
+	}
+	return &Query{expr: expr, root: root}, nil
+}
+
+// Match returns true if the group of txs matches the query
+func (q *Query) Match(group []*Tx) bool {
+	return q.root.match(group)
+}
+
+// String returns the expression of the query
+func (q *Query) String() string {
+	return q.expr
+}
+
+/* Tokens */
+
+type queryTokenKind int
+
+const (
+	qtokEOF queryTokenKind = iota
+	qtokWord
+	qtokString
+	qtokOp
+	qtokLParen
+	qtokRParen
+)
+
+type queryToken struct {
+	kind  queryTokenKind
+	value string
+	col   int
+}
+
+func (tok queryToken) String() string {
+	switch tok.kind {
+	case qtokEOF:
+		return "end of query"
+	case qtokString:
+		return strconv.Quote(tok.value)
+	default:
+		return fmt.Sprintf("%q", tok.value)
+	}
+}
+
+func (tok queryToken) errorf(format string, args ...any) error {
+	return fmt.Errorf("col %d: %s", tok.col, fmt.Sprintf(format, args...))
+}
+
+func (tok queryToken) isKeyword(keyword string) bool {
+	return tok.kind == qtokWord && tok.value == keyword
+}
+
+var queryOps = []string{"==", "!=", "<=", ">=", "!~", "<", ">", "~"}
+
+func lexQuery(expr string) ([]queryToken, error) {
+	var (
+		tokens []queryToken
+		runes  = []rune(expr)
+	)
+
+	for i := 0; i < len(runes); {
+		r := runes[i]
+		col := i + 1
+		switch {
+		case unicode.IsSpace(r):
+			i++
+		case r == '(':
+			tokens = append(tokens, queryToken{kind: qtokLParen, value: "(", col: col})
+			i++
+		case r == ')':
+			tokens = append(tokens, queryToken{kind: qtokRParen, value: ")", col: col})
+			i++
+		case r == '"' || r == '\'':
+			var sb strings.Builder
+			quote := r
+			i++
+			for ; i < len(runes) && runes[i] != quote; i++ {
+				if runes[i] == '\\' && i+1 < len(runes) && runes[i+1] == quote {
+					i++
+				}
+				sb.WriteRune(runes[i])
+			}
+			if i == len(runes) {
+				return nil, fmt.Errorf("col %d: unterminated string", col)
+			}
+			i++
+			tokens = append(tokens, queryToken{kind: qtokString, value: sb.String(), col: col})
+		case strings.ContainsRune("<>=!~", r):
+			op := ""
+			for _, o := range queryOps {
+				if strings.HasPrefix(string(runes[i:]), o) {
+					op = o
+					break
+				}
+			}
+			if op == "" {
+				return nil, fmt.Errorf("col %d: unknown operator %q", col, r)
+			}
+			tokens = append(tokens, queryToken{kind: qtokOp, value: op, col: col})
+			i += len(op)
+		default:
+			start := i
+			for i < len(runes) && !unicode.IsSpace(runes[i]) && !strings.ContainsRune("()\"'<>=!~", runes[i]) {
+				i++
+			}
+			word := string(runes[start:i])
+			kind := qtokWord
+			if word == "eq" || word == "ne" {
+				kind = qtokOp
+			}
+			tokens = append(tokens, queryToken{kind: kind, value: word, col: col})
+		}
+	}
+
+	return append(tokens, queryToken{kind: qtokEOF, col: len(runes) + 1}), nil
+}
+
+/* Parser */
+
+type queryParser struct {
+	tokens []queryToken
+	pos    int
+}
+
+func (p *queryParser) peek() queryToken {
+	return p.tokens[p.pos]
+}
+
+func (p *queryParser) next() queryToken {
+	tok := p.tokens[p.pos]
+	if tok.kind != qtokEOF {
+		p.pos++
+	}
+	return tok
+}
+
+func (p *queryParser) parseOr() (queryNode, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for p.peek().isKeyword("or") {
+		p.next()
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		left = queryOr{left, right}
+	}
+	return left, nil
+}
+
+func (p *queryParser) parseAnd() (queryNode, error) {
+	left, err := p.parseNot()
+	if err != nil {
+		return nil, err
+	}
+	for p.peek().isKeyword("and") {
+		p.next()
+		right, err := p.parseNot()
+		if err != nil {
+			return nil, err
+		}
+		left = queryAnd{left, right}
+	}
+	return left, nil
+}
+
+func (p *queryParser) parseNot() (queryNode, error) {
+	if p.peek().isKeyword("not") {
+		p.next()
+		node, err := p.parseNot()
+		if err != nil {
+			return nil, err
+		}
+		return queryNot{node}, nil
+	}
+	return p.parsePrimary()
+}
+
+func (p *queryParser) parsePrimary() (queryNode, error) {
+	tok := p.next()
+	switch tok.kind {
+	case qtokLParen:
+		node, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if closing := p.next(); closing.kind != qtokRParen {
+			return nil, closing.errorf("expected \")\", got %s", closing)
+		}
+		return node, nil
+	case qtokWord:
+		return p.parseTest(tok)
+	default:
+		return nil, tok.errorf("expected a record selector, got %s", tok)
+	}
+}
+
+// parseTest parses a record selector and, if present, its comparison
+func (p *queryParser) parseTest(lhs queryToken) (queryNode, error) {
+	var (
+		test queryTest
+		err  error
+	)
+	if lhs.value == "vxid" {
+		test.vxid = true
+	} else if test.selector, err = parseSelector(lhs); err != nil {
+		return nil, err
+	}
+
+	if p.peek().kind != qtokOp {
+		if test.vxid {
+			return nil, p.peek().errorf("vxid requires a numeric comparison")
+		}
+		return test, nil
+	}
+
+	opTok := p.next()
+	test.op = opTok.value
+	rhs := p.next()
+	if rhs.kind != qtokWord && rhs.kind != qtokString {
+		return nil, rhs.errorf("expected an operand after %s, got %s", test.op, rhs)
+	}
+	test.str = rhs.value
+
+	switch test.op {
+	case "~", "!~":
+		if test.vxid {
+			return nil, opTok.errorf("vxid requires a numeric comparison")
+		}
+		if test.re, err = regexp.Compile(rhs.value); err != nil {
+			return nil, rhs.errorf("invalid regular expression: %s", err)
+		}
+	case "eq", "ne":
+		if test.vxid {
+			return nil, opTok.errorf("vxid requires a numeric comparison")
+		}
+	default:
+		if i, err := strconv.ParseInt(rhs.value, 0, 64); err == nil {
+			test.num = float64(i)
+		} else if f, err := strconv.ParseFloat(rhs.value, 64); err == nil && !test.vxid {
+			test.num, test.float = f, true
+		} else {
+			return nil, rhs.errorf("%s requires a number, got %s", test.op, rhs)
+		}
+	}
+	return test, nil
+}
+
+// recordSelector selects records: {level}taglist:prefix[field]
+type recordSelector struct {
+	level    int  // 0 for any level
+	levelCmp byte // '=' exact, '+' at least, '-' at most
+	tags     map[string]bool
+	prefix   string
+	field    int // 1 based, 0 for the whole value
+}
+
+func parseSelector(tok queryToken) (recordSelector, error) {
+	sel := recordSelector{levelCmp: '=', tags: make(map[string]bool)}
+	s := tok.value
+
+	// {level}
+	if strings.HasPrefix(s, "{") {
+		end := strings.Index(s, "}")
+		if end < 0 {
+			return sel, tok.errorf("unterminated level in %q", tok.value)
+		}
+		level := s[1:end]
+		if strings.HasSuffix(level, "+") || strings.HasSuffix(level, "-") {
+			sel.levelCmp = level[len(level)-1]
+			level = level[:len(level)-1]
+		}
+		n, err := strconv.Atoi(level)
+		if err != nil || n < 0 {
+			return sel, tok.errorf("invalid level in %q", tok.value)
+		}
+		sel.level = n
+		s = s[end+1:]
+	}
+
+	// [field]
+	if strings.HasSuffix(s, "]") {
+		start := strings.LastIndex(s, "[")
+		if start < 0 {
+			return sel, tok.errorf("invalid field in %q", tok.value)
+		}
+		n, err := strconv.Atoi(s[start+1 : len(s)-1])
+		if err != nil || n < 1 {
+			return sel, tok.errorf("invalid field in %q, fields start at 1", tok.value)
+		}
+		sel.field = n
+		s = s[:start]
+	}
+
+	// :prefix
+	taglist, prefix, hasPrefix := strings.Cut(s, ":")
+	if hasPrefix {
+		if prefix == "" {
+			return sel, tok.errorf("empty record prefix in %q", tok.value)
+		}
+		sel.prefix = prefix
+	}
+
+	for _, pattern := range strings.Split(taglist, ",") {
+		matched := false
+		for _, tag := range Tags {
+			if tag != "" && globMatch(pattern, tag) {
+				sel.tags[strings.ToLower(tag)] = true
+				matched = true
+			}
+		}
+		if !matched {
+			return sel, tok.errorf("unknown tag %q", pattern)
+		}
+	}
+	return sel, nil
+}
+
+// globMatch matches a tag against a pattern with an optional * (case insensitive): Req*, *Header
+func globMatch(pattern, tag string) bool {
+	pattern, tag = strings.ToLower(pattern), strings.ToLower(tag)
+	before, after, found := strings.Cut(pattern, "*")
+	if !found {
+		return pattern == tag
+	}
+	return len(tag) >= len(before)+len(after) && strings.HasPrefix(tag, before) && strings.HasSuffix(tag, after)
+}
+
+/* Nodes */
+
+type queryNode interface {
+	match(group []*Tx) bool
+}
+
+type queryAnd struct{ left, right queryNode }
+
+func (n queryAnd) match(group []*Tx) bool { return n.left.match(group) && n.right.match(group) }
+
+type queryOr struct{ left, right queryNode }
+
+func (n queryOr) match(group []*Tx) bool { return n.left.match(group) || n.right.match(group) }
+
+type queryNot struct{ node queryNode }
+
+func (n queryNot) match(group []*Tx) bool { return !n.node.match(group) }
+
+// queryTest matches if any record of any tx of the group passes the comparison
+type queryTest struct {
+	vxid     bool
+	selector recordSelector
+	op       string // Empty when only testing the presence of the record
+	str      string
+	num      float64
+	float    bool
+	re       *regexp.Regexp
+}
+
+func (n queryTest) match(group []*Tx) bool {
+	for _, t := range group {
+		if n.vxid {
+			if n.compareNumber(float64(t.Vxid)) {
+				return true
+			}
+			continue
+		}
+		if !n.selector.matchLevel(t.Level()) {
+			continue
+		}
+		for _, rec := range t.Records() {
+			if !n.selector.tags[strings.ToLower(rec.Tag)] {
+				continue
+			}
+			if value, ok := n.selector.value(rec.Value); ok && n.compare(value) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func (s recordSelector) matchLevel(level int) bool {
+	switch {
+	case s.level == 0:
+		return true
+	case s.levelCmp == '+':
+		return level >= s.level
+	case s.levelCmp == '-':
+		return level <= s.level
+	default:
+		return level == s.level
+	}
+}
+
+// value returns the part of the record value selected by the prefix and the field
+func (s recordSelector) value(v string) (string, bool) {
+	if s.prefix != "" {
+		name, rest, found := strings.Cut(v, ":")
+		if !found || !strings.EqualFold(strings.TrimSpace(name), s.prefix) {
+			return "", false
+		}
+		v = strings.TrimSpace(rest)
+	}
+	if s.field > 0 {
+		fields := strings.Fields(v)
+		if s.field > len(fields) {
+			return "", false
+		}
+		v = fields[s.field-1]
+	}
+	return v, true
+}
+
+func (n queryTest) compare(value string) bool {
+	switch n.op {
+	case "":
+		return true
+	case "eq":
+		return value == n.str
+	case "ne":
+		return value != n.str
+	case "~":
+		return n.re.MatchString(value)
+	case "!~":
+		return !n.re.MatchString(value)
+	}
+
+	// Numeric comparisons, like varnishlog the record is parsed as the type of the operand
+	value = strings.TrimSpace(value)
+	var (
+		v   float64
+		err error
+	)
+	if n.float {
+		v, err = strconv.ParseFloat(value, 64)
+	} else {
+		var i int64
+		i, err = strconv.ParseInt(value, 0, 64)
+		v = float64(i)
+	}
+	return err == nil && n.compareNumber(v)
+}
+
+func (n queryTest) compareNumber(v float64) bool {
+	switch n.op {
+	case "==":
+		return v == n.num
+	case "!=":
+		return v != n.num
+	case "<":
+		return v < n.num
+	case "<=":
+		return v <= n.num
+	case ">":
+		return v > n.num
+	case ">=":
+		return v >= n.num
+	}
+	return false
+}
@@ -0,0 +1,82 @@
+package vsl
+
+import (
+	"strings"
+	"testing"
+)
+
+// TestQueryMatch tests VSL queries on the group of a request and its bereq
+func TestQueryMatch(t *testing.T) {
+	group := []*Tx{parseTestLog(t, testReqLog), parseTestLog(t, testBereqLog)}
+
+	tests := []struct {
+		query    string
+		expected bool
+	}{
+		{`ReqURL eq "/esi/"`, true},
+		{`ReqURL ~ "^/esi/\?utm"`, true},
+		{`ReqURL ne "/esi/"`, true}, // The first ReqURL differs
+		{`RespStatus == 200`, true},
+		{`RespStatus >= 500`, false},
+		{`RespStatus == 200.0`, true},
+		{`ReqHeader:host eq "www.example1.com"`, true},
+		{`ReqHeader:X-Foo`, true},
+		{`ReqHeader:X-Missing`, false},
+		{`not ReqHeader:X-Missing`, true},
+		{`BerespHeader:Content-Length == 13 and RespStatus == 200`, true},
+		{`{1}BerespStatus == 200`, false},
+		{`{2}BerespStatus == 200`, true},
+		{`{2-}ReqMethod eq GET`, true},
+		{`{2+}ReqMethod eq GET`, false},
+		{`Timestamp:Fetch[2] > 0.003`, true},
+		{`Timestamp:Fetch[2] > 0.004`, false},
+		{`Timestamp:Fetch[9]`, false},
+		{`ReqAcct[5] == 13`, true},
+		{`Req*:Host ~ "example1"`, true},
+		{`*Status == 200 and BackendOpen[2] eq boot.default`, true},
+		{`Resp*,Bereq*:Host ~ "example1"`, true},
+		{`vxid == 32771`, true},
+		{`vxid == 1 or (FetchError and not VCL_Error)`, true},
+		{`Notice ~ "x" or VdpAcct`, false}, // Tags of Varnish 7
+	}
+
+	for _, tt := range tests {
+		q, err := ParseQuery(tt.query)
+		if err != nil {
+			t.Errorf("ParseQuery(%q) returned an error: %s", tt.query, err)
+			continue
+		}
+		if got := q.Match(group); got != tt.expected {
+			t.Errorf("%q: expected %t, got %t", tt.query, tt.expected, got)
+		}
+	}
+}
+
+// TestQueryParseErrors tests that malformed queries report the column of the problem
+func TestQueryParseErrors(t *testing.T) {
+	tests := []struct {
+		query    string
+		expected string
+	}{
+		{`ReqUrl2 eq "/"`, `col 1: unknown tag "ReqUrl2"`},
+		{`RespStatus >= `, `col 15: expected an operand after >=`},
+		{`RespStatus >= abc`, `col 15: >= requires a number`},
+		{`vxid ~ "1"`, `col 6: vxid requires a numeric comparison`},
+		{`{x}ReqURL`, `col 1: invalid level`},
+		{`ReqURL[0] eq "/"`, `col 1: invalid field`},
+		{`ReqURL ~ "("`, `col 10: invalid regular expression`},
+		{`(ReqURL`, `col 8: expected ")"`},
+		{`ReqURL ReqMethod`, `col 8: unexpected "ReqMethod"`},
+	}
+
+	for _, tt := range tests {
+		_, err := ParseQuery(tt.query)
+		if err == nil {
+			t.Errorf("ParseQuery(%q): expected an error", tt.query)
+			continue
+		}
+		if !strings.HasPrefix(err.Error(), tt.expected) {
+			t.Errorf("ParseQuery(%q): expected %q, got %q", tt.query, tt.expected, err.Error())
+		}
+	}
+}
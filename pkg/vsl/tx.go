@@ -0,0 +1,152 @@
+package vsl
+
+import (
+	"strings"
+	"time"
+)
+
+// Tx represents a single transaction
+type Tx struct {
+	Txid          string
+	Vxid          uint64
+	RecordType    string // req, bereq, sess
+	Reason        string // rxreq, fetch, esi, ...
+	Method        string
+	Host          string
+	Url           string
+	StatusCode    int
+	StatusReason  string
+	Timestamps    []Timestamp
+	Transitions   []VCLTransition
+	TTL           []TTLData
+	Accounting    RequestAccounting
+	ReqHeaders    HeaderSet
+	RespHeaders   HeaderSet
+	BereqHeaders  HeaderSet
+	BerespHeaders HeaderSet
+	ObjHeaders    HeaderSet
+	HeaderChanges []HeaderChange
+	CacheOutcome  CacheOutcome
+	HitVxid       uint64 // vxid of the bereq that stored the object served on hits
+	Backend       BackendConnection
+	Client        ClientInfo
+	Events        []Event
+	Links         []Link   // Children of the tx: bereqs, ESI subrequests, ...
+	RawTx         []string // Lines of the tx as logged by varnishlog
+	Incomplete    bool     // End was never logged: VSL overflow, truncated input, ...
+
+	// Parsed from RawTx the first time the tx is queried
+	records []Record
+	parsed  bool // The records were parsed, there may be none
+	level   int
+}
+
+type Timestamp struct {
+	EventLabel string        // Start, Req, Fetch, Process, Resp, ...
+	Absolute   time.Time     // Absolute time of the timestamp
+	SinceStart time.Duration // Duration since the start of the tx
+	SinceLast  time.Duration // Duration since the last timestamp
+}
+
+type TTLData struct {
+	Source      string    // "RFC", "VCL" or "HFP"
+	TTL         int       // Time-to-live
+	Grace       int       // Grace period
+	Keep        int       // Keep period
+	Reference   time.Time // Reference time for TTL
+	Age         int       // Age (incl Age: header value)
+	Date        time.Time // Date header
+	Expires     time.Time // Expires header
+	MaxAge      int       // Max-Age from Cache-Control header
+	CacheStatus string    // "cacheable" or "uncacheable"
+}
+
+type VCLTransition struct {
+	Call   string // RECV, HASH
+	Return string // synth, lookup
+}
+
+// RequestAccounting holds the ReqAcct or BereqAcct sizes in bytes
+type RequestAccounting struct {
+	HeaderBytesReceived    int64
+	BodyBytesReceived      int64
+	HeaderBytesTransmitted int64
+	BodyBytesTransmitted   int64
+}
+
+// Link is a child of a tx as logged in its Link record
+//
+//	--  Link           req 13 esi 2
+type Link struct {
+	Txid       string // Txid of the child: 13_2
+	Vxid       uint64 // Vxid of the child: 13
+	RecordType string // req or bereq
+	Reason     string // fetch, esi, bgfetch, ...
+}
+
+// SumOfSinceLast returns the sum of all the SinceLast of this Tx Timestamps
+func (t Tx) SumOfSinceLast() time.Duration {
+	var total time.Duration
+	for _, ts := range t.Timestamps {
+		total += ts.SinceLast
+	}
+	return total
+}
+
+// StartTime returns the absolute time of the first timestamp of the tx, zero if it has none
+func (t Tx) StartTime() time.Time {
+	if len(t.Timestamps) == 0 {
+		return time.Time{}
+	}
+	return t.Timestamps[0].Absolute
+}
+
+// Received returns the total bytes received, headers and body
+func (a RequestAccounting) Received() int64 {
+	return a.HeaderBytesReceived + a.BodyBytesReceived
+}
+
+// Transmitted returns the total bytes transmitted, headers and body
+func (a RequestAccounting) Transmitted() int64 {
+	return a.HeaderBytesTransmitted + a.BodyBytesTransmitted
+}
+
+// Records returns the records of the tx from its raw log, the group header is skipped.
+// They are parsed once, so RawTx must not be modified after the first call.
+func (t *Tx) Records() []Record {
+	if t.parsed {
+		return t.records
+	}
+
+	kind := byte('c')
+	if t.RecordType == "bereq" {
+		kind = 'b'
+	}
+
+	records := make([]Record, 0, len(t.RawTx))
+	for _, line := range t.RawTx {
+		parts := strings.Fields(line)
+		if len(parts) < 2 || isGroupHeader(parts) {
+			continue
+		}
+		records = append(records, Record{Vxid: t.Vxid, Tag: parts[1], Kind: kind, Value: recordValue(line)})
+	}
+	t.records, t.parsed = records, true
+	return records
+}
+
+// Level returns the nesting level of the tx in its group, 1 for the root
+func (t *Tx) Level() int {
+	if t.level != 0 {
+		return t.level
+	}
+
+	t.level = 1
+	for _, line := range t.RawTx {
+		if parts := strings.Fields(line); len(parts) > 0 {
+			t.level = groupLevel(parts[0])
+			break
+		}
+	}
+	return t.level
+}